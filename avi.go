@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+)
+
+const (
+	AVI_CODEC_RAW   = iota /* uncompressed 24bit rgb; large but lossless */
+	AVI_CODEC_MJPEG        /* every frame is a jpeg */
+)
+
+const (
+	AVI_FPS               = 60 /* the emulator always runs 60 frames per second (see GoStation::cyclesPerFrame) */
+	AVI_SAMPLE_RATE       = 44100
+	AVI_CHANNELS          = 2
+	AVI_SAMPLES_PER_FRAME = AVI_SAMPLE_RATE / AVI_FPS
+	AVI_JPEG_QUALITY      = 90
+	AVI_MAX_SIZE          = 1 << 30 /* avi 1.0 files larger than 1GiB aren't played by everything */
+)
+
+/*
+	https://learn.microsoft.com/en-us/windows/win32/directshow/avi-riff-file-reference
+
+Records the display output (and the audio) into an avi file:
+
+	RIFF 'AVI '
+	  LIST 'hdrl'
+	    'avih'                   main header
+	    LIST 'strl' 'strh' 'strf' video stream (BITMAPINFOHEADER)
+	    LIST 'strl' 'strh' 'strf' audio stream (WAVEFORMATEX; 16bit stereo pcm)
+	  LIST 'movi'
+	    '00dc' / '01wb'          one video and one audio chunk per frame
+	  'idx1'                     offsets of all chunks
+
+Frames are scaled (nearest neighbour) to a fixed size so resolution changes (GP1(08h)) don't break the
+video. Sizes and frame counts in the headers are filled in by AVIRecorder::Close.
+*/
+type AVIRecorder struct {
+	file   *os.File
+	writer *bufio.Writer
+	offset int /* bytes written so far */
+
+	codec  int
+	width  int
+	height int
+
+	frames  int
+	samples int
+	moviPos int    /* offset of the 'movi' fourcc */
+	index   []byte /* idx1 entries */
+	patches []int  /* offsets of the size fields of open RIFF/LIST chunks */
+
+	/* offsets of header fields which are only known at the end */
+	totalFramesPos int
+	videoLengthPos int
+	audioLengthPos int
+
+	scaled   *image.RGBA
+	frame    []byte /* scaled frame (raw codec) */
+	jpegData bytes.Buffer
+	silence  []int16
+}
+
+func NewAVIRecorder(path string, codec, width, height int) (*AVIRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &AVIRecorder{
+		file,
+		bufio.NewWriter(file),
+		0,
+		codec,
+		width,
+		height,
+		0,
+		0,
+		0,
+		nil,
+		nil,
+		0,
+		0,
+		0,
+		image.NewRGBA(image.Rect(0, 0, width, height)),
+		make([]byte, width*height*3),
+		bytes.Buffer{},
+		make([]int16, AVI_SAMPLES_PER_FRAME*AVI_CHANNELS),
+	}
+
+	recorder.WriteHeaders()
+
+	return recorder, nil
+}
+
+func (recorder *AVIRecorder) Write(data ...interface{}) {
+	for _, value := range data {
+		switch v := value.(type) {
+		case string: // fourcc
+			recorder.writer.WriteString(v)
+			recorder.offset += len(v)
+		case []byte:
+			recorder.writer.Write(v)
+			recorder.offset += len(v)
+		default:
+			binary.Write(recorder.writer, binary.LittleEndian, v)
+			recorder.offset += binary.Size(v)
+		}
+	}
+}
+
+/* starts a RIFF or LIST chunk; its size gets filled in by EndList */
+func (recorder *AVIRecorder) BeginList(list, fourcc string) {
+	recorder.Write(list)
+	recorder.patches = append(recorder.patches, recorder.offset)
+	recorder.Write(uint32(0), fourcc)
+}
+
+func (recorder *AVIRecorder) EndList() {
+	pos := recorder.patches[len(recorder.patches)-1]
+	recorder.patches = recorder.patches[:len(recorder.patches)-1]
+
+	recorder.Patch(pos, uint32(recorder.offset-pos-4))
+}
+
+/* overwrites a word which was already written */
+func (recorder *AVIRecorder) Patch(pos int, value uint32) {
+	recorder.writer.Flush()
+
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], value)
+	recorder.file.WriteAt(data[:], int64(pos))
+}
+
+func (recorder *AVIRecorder) WriteHeaders() {
+	frameSize := uint32(recorder.width * recorder.height * 3)
+	bytesPerSecond := uint32(AVI_SAMPLE_RATE * AVI_CHANNELS * 2)
+
+	compression, handler := uint32(0), "DIB "
+	if recorder.codec == AVI_CODEC_MJPEG {
+		compression, handler = binary.LittleEndian.Uint32([]byte("MJPG")), "MJPG"
+	}
+
+	recorder.BeginList("RIFF", "AVI ")
+	recorder.BeginList("LIST", "hdrl")
+
+	recorder.Write("avih", uint32(56),
+		uint32(1000000/AVI_FPS), // dwMicroSecPerFrame
+		frameSize*AVI_FPS+bytesPerSecond,
+		uint32(0),    // dwPaddingGranularity
+		uint32(0x10), // dwFlags (AVIF_HASINDEX)
+	)
+	recorder.totalFramesPos = recorder.offset
+	recorder.Write(
+		uint32(0), // dwTotalFrames
+		uint32(0), // dwInitialFrames
+		uint32(2), // dwStreams
+		frameSize, // dwSuggestedBufferSize
+		uint32(recorder.width),
+		uint32(recorder.height),
+		[4]uint32{},
+	)
+
+	recorder.BeginList("LIST", "strl")
+	recorder.Write("strh", uint32(56),
+		"vids",
+		handler,
+		uint32(0), // dwFlags
+		uint16(0), // wPriority
+		uint16(0), // wLanguage
+		uint32(0), // dwInitialFrames
+		uint32(1), // dwScale
+		uint32(AVI_FPS),
+		uint32(0), // dwStart
+	)
+	recorder.videoLengthPos = recorder.offset
+	recorder.Write(
+		uint32(0), // dwLength
+		frameSize,
+		uint32(0xffffffff), // dwQuality (default)
+		uint32(0),          // dwSampleSize
+		[4]uint16{0, 0, uint16(recorder.width), uint16(recorder.height)},
+	)
+	recorder.Write("strf", uint32(40),
+		uint32(40), // biSize
+		int32(recorder.width),
+		int32(recorder.height), // bottom up (raw frames)
+		uint16(1),              // biPlanes
+		uint16(24),             // biBitCount
+		compression,
+		frameSize,
+		[4]uint32{},
+	)
+	recorder.EndList()
+
+	recorder.BeginList("LIST", "strl")
+	recorder.Write("strh", uint32(56),
+		"auds",
+		uint32(0),
+		uint32(0),
+		uint16(0),
+		uint16(0),
+		uint32(0),
+		uint32(AVI_CHANNELS*2), // dwScale (block align)
+		bytesPerSecond,         // dwRate
+		uint32(0),
+	)
+	recorder.audioLengthPos = recorder.offset
+	recorder.Write(
+		uint32(0), // dwLength (in blocks)
+		bytesPerSecond,
+		uint32(0xffffffff),
+		uint32(AVI_CHANNELS*2), // dwSampleSize
+		[4]uint16{},
+	)
+	recorder.Write("strf", uint32(18),
+		uint16(1), // wFormatTag (pcm)
+		uint16(AVI_CHANNELS),
+		uint32(AVI_SAMPLE_RATE),
+		bytesPerSecond,
+		uint16(AVI_CHANNELS*2), // nBlockAlign
+		uint16(16),             // wBitsPerSample
+		uint16(0),              // cbSize
+	)
+	recorder.EndList()
+
+	recorder.EndList() // hdrl
+
+	recorder.BeginList("LIST", "movi")
+	recorder.moviPos = recorder.offset - 4
+}
+
+/*
+Adds a frame; samples are interleaved 16bit stereo at AVI_SAMPLE_RATE (AVI_SAMPLES_PER_FRAME per frame).
+Without samples the frame is silent.
+*/
+func (recorder *AVIRecorder) WriteFrame(img *image.RGBA, samples []int16) error {
+	if recorder.offset >= AVI_MAX_SIZE {
+		return fmt.Errorf("video is larger than %d bytes", AVI_MAX_SIZE)
+	}
+
+	recorder.Scale(img)
+
+	var data []byte
+	if recorder.codec == AVI_CODEC_MJPEG {
+		recorder.jpegData.Reset()
+		if err := jpeg.Encode(&recorder.jpegData, recorder.scaled, &jpeg.Options{Quality: AVI_JPEG_QUALITY}); err != nil {
+			return err
+		}
+		data = recorder.jpegData.Bytes()
+	} else {
+		// rows from bottom to top, pixels in bgr order
+		for y := 0; y < recorder.height; y += 1 {
+			src := recorder.scaled.Pix[(recorder.height-1-y)*recorder.scaled.Stride:]
+			dst := recorder.frame[y*recorder.width*3:]
+
+			for x := 0; x < recorder.width; x += 1 {
+				dst[x*3+0] = src[x*4+2]
+				dst[x*3+1] = src[x*4+1]
+				dst[x*3+2] = src[x*4+0]
+			}
+		}
+		data = recorder.frame
+	}
+	recorder.WriteChunk("00dc", data, true)
+
+	if samples == nil {
+		samples = recorder.silence
+	}
+
+	audio := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(audio[i*2:], uint16(sample))
+	}
+	recorder.WriteChunk("01wb", audio, true)
+
+	recorder.frames += 1
+	recorder.samples += len(samples) / AVI_CHANNELS
+
+	return nil
+}
+
+/* scales img to the size of the video (nearest neighbour) */
+func (recorder *AVIRecorder) Scale(img *image.RGBA) {
+	srcWidth := img.Bounds().Dx()
+	srcHeight := img.Bounds().Dy()
+
+	for y := 0; y < recorder.height; y += 1 {
+		srcY := y * srcHeight / recorder.height
+
+		for x := 0; x < recorder.width; x += 1 {
+			srcX := x * srcWidth / recorder.width
+
+			copy(recorder.scaled.Pix[y*recorder.scaled.Stride+x*4:][:4], img.Pix[srcY*img.Stride+srcX*4:][:4])
+		}
+	}
+}
+
+func (recorder *AVIRecorder) WriteChunk(fourcc string, data []byte, keyframe bool) {
+	var flags uint32
+	ModifyBit(&flags, 4, keyframe) // AVIIF_KEYFRAME
+
+	entry := make([]byte, 16)
+	copy(entry, fourcc)
+	binary.LittleEndian.PutUint32(entry[4:], flags)
+	binary.LittleEndian.PutUint32(entry[8:], uint32(recorder.offset-recorder.moviPos))
+	binary.LittleEndian.PutUint32(entry[12:], uint32(len(data)))
+	recorder.index = append(recorder.index, entry...)
+
+	recorder.Write(fourcc, uint32(len(data)), data)
+	if len(data)%2 != 0 {
+		// chunks are word aligned
+		recorder.Write(uint8(0))
+	}
+}
+
+/* writes the index, fills in the headers and closes the file */
+func (recorder *AVIRecorder) Close() error {
+	recorder.EndList() // movi
+
+	recorder.Write("idx1", uint32(len(recorder.index)), recorder.index)
+
+	recorder.EndList() // RIFF
+
+	recorder.Patch(recorder.totalFramesPos, uint32(recorder.frames))
+	recorder.Patch(recorder.videoLengthPos, uint32(recorder.frames))
+	recorder.Patch(recorder.audioLengthPos, uint32(recorder.samples))
+
+	if err := recorder.writer.Flush(); err != nil {
+		recorder.file.Close()
+		return err
+	}
+
+	return recorder.file.Close()
+}
+
+/* starts recording the display output into an avi file (see AVIRecorder) */
+func (gostation *GoStation) StartVideo(path string, codec int) error {
+	gostation.StopVideo()
+
+	recorder, err := NewAVIRecorder(path, codec, DISPLAY_MAX_WIDTH, DISPLAY_MAX_HEIGHT)
+	if err != nil {
+		return err
+	}
+
+	gostation.video = recorder
+	gostation.videoDisplay = NewDisplayOutput(DEINTERLACE_WEAVE)
+
+	return nil
+}
+
+func (gostation *GoStation) StopVideo() error {
+	if gostation.video == nil {
+		return nil
+	}
+
+	err := gostation.video.Close()
+	fmt.Printf("[GoStation::StopVideo] recorded %d frames\n", gostation.video.frames)
+
+	gostation.video = nil
+	gostation.videoDisplay = nil
+
+	return err
+}
+
+func (gostation *GoStation) Recording() bool {
+	return gostation.video != nil
+}
+
+/* called at the end of each frame with the samples the spu produced during it */
+func (gostation *GoStation) RecordVideoFrame(samples []int16) {
+	if gostation.video == nil {
+		return
+	}
+
+	if err := gostation.video.WriteFrame(gostation.videoDisplay.Render(gostation.GPU), samples); err != nil {
+		fmt.Printf("[GoStation::RecordVideoFrame] %s; recording stopped\n", err)
+		gostation.StopVideo()
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+https://psx-spx.consoledev.net/kernelbios/#bios-function-summary
+
+Kernel functions are called by jumping to A0h, B0h or C0h with the function number in R9; system calls
+are made by the syscall instruction with the function number in R4. Arguments are passed in R4..R7 and
+on the stack (see BIOSFunctionArgument), the result is returned in R2.
+
+The tables have the name, the types of the arguments and the return type of every function which does
+something; numbers which aren't in the tables just return zero or crash with SystemError.
+*/
+const (
+	BIOS_VOID        = iota /* return type of functions which don't return anything */
+	BIOS_INT                /* signed decimal */
+	BIOS_HEX                /* pointers, flags and other raw words */
+	BIOS_STRING             /* pointer to a zero terminated string */
+	BIOS_FORMAT             /* printf format string, followed by as many arguments as it has conversions */
+	BIOS_CHAR               /* character in the low byte */
+	BIOS_FD                 /* file descriptor */
+	BIOS_EVENT              /* event handle (F1000000h and up) */
+	BIOS_EVENT_CLASS        /* what an event belongs to (F0000001h=vblank etc) */
+	BIOS_EVENT_SPEC         /* what happened (bits) */
+	BIOS_EVENT_MODE         /* what delivering the event does */
+)
+
+const BIOS_STRING_MAX_LENGTH = 64
+
+type BIOSFunction struct {
+	Name   string
+	Args   []int /* BIOS_* */
+	Return int
+}
+
+var BIOS_A_FUNCTIONS = map[uint32]*BIOSFunction{
+	0x00: {"FileOpen", []int{BIOS_STRING, BIOS_HEX}, BIOS_FD},
+	0x01: {"FileSeek", []int{BIOS_FD, BIOS_INT, BIOS_INT}, BIOS_INT},
+	0x02: {"FileRead", []int{BIOS_FD, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x03: {"FileWrite", []int{BIOS_FD, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x04: {"FileClose", []int{BIOS_FD}, BIOS_INT},
+	0x05: {"FileIoctl", []int{BIOS_FD, BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x06: {"exit", []int{BIOS_INT}, BIOS_VOID},
+	0x07: {"FileGetDeviceFlag", []int{BIOS_FD}, BIOS_HEX},
+	0x08: {"FileGetc", []int{BIOS_FD}, BIOS_CHAR},
+	0x09: {"FilePutc", []int{BIOS_CHAR, BIOS_FD}, BIOS_INT},
+	0x0a: {"todigit", []int{BIOS_CHAR}, BIOS_INT},
+	0x0b: {"atof", []int{BIOS_STRING}, BIOS_HEX},
+	0x0c: {"strtoul", []int{BIOS_STRING, BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x0d: {"strtol", []int{BIOS_STRING, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x0e: {"abs", []int{BIOS_INT}, BIOS_INT},
+	0x0f: {"labs", []int{BIOS_INT}, BIOS_INT},
+	0x10: {"atoi", []int{BIOS_STRING}, BIOS_INT},
+	0x11: {"atol", []int{BIOS_STRING}, BIOS_INT},
+	0x12: {"atob", []int{BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x13: {"SaveState", []int{BIOS_HEX}, BIOS_INT},
+	0x14: {"RestoreState", []int{BIOS_HEX, BIOS_HEX}, BIOS_VOID},
+	0x15: {"strcat", []int{BIOS_HEX, BIOS_STRING}, BIOS_HEX},
+	0x16: {"strncat", []int{BIOS_HEX, BIOS_STRING, BIOS_INT}, BIOS_HEX},
+	0x17: {"strcmp", []int{BIOS_STRING, BIOS_STRING}, BIOS_INT},
+	0x18: {"strncmp", []int{BIOS_STRING, BIOS_STRING, BIOS_INT}, BIOS_INT},
+	0x19: {"strcpy", []int{BIOS_HEX, BIOS_STRING}, BIOS_HEX},
+	0x1a: {"strncpy", []int{BIOS_HEX, BIOS_STRING, BIOS_INT}, BIOS_HEX},
+	0x1b: {"strlen", []int{BIOS_STRING}, BIOS_INT},
+	0x1c: {"index", []int{BIOS_STRING, BIOS_CHAR}, BIOS_HEX},
+	0x1d: {"rindex", []int{BIOS_STRING, BIOS_CHAR}, BIOS_HEX},
+	0x1e: {"strchr", []int{BIOS_STRING, BIOS_CHAR}, BIOS_HEX},
+	0x1f: {"strrchr", []int{BIOS_STRING, BIOS_CHAR}, BIOS_HEX},
+	0x20: {"strpbrk", []int{BIOS_STRING, BIOS_STRING}, BIOS_HEX},
+	0x21: {"strspn", []int{BIOS_STRING, BIOS_STRING}, BIOS_INT},
+	0x22: {"strcspn", []int{BIOS_STRING, BIOS_STRING}, BIOS_INT},
+	0x23: {"strtok", []int{BIOS_STRING, BIOS_STRING}, BIOS_HEX},
+	0x24: {"strstr", []int{BIOS_STRING, BIOS_STRING}, BIOS_HEX},
+	0x25: {"toupper", []int{BIOS_CHAR}, BIOS_CHAR},
+	0x26: {"tolower", []int{BIOS_CHAR}, BIOS_CHAR},
+	0x27: {"bcopy", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_VOID},
+	0x28: {"bzero", []int{BIOS_HEX, BIOS_INT}, BIOS_VOID},
+	0x29: {"bcmp", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x2a: {"memcpy", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x2b: {"memset", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x2c: {"memmove", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x2d: {"memcmp", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x2e: {"memchr", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x2f: {"rand", []int{}, BIOS_INT},
+	0x30: {"srand", []int{BIOS_HEX}, BIOS_VOID},
+	0x31: {"qsort", []int{BIOS_HEX, BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_VOID},
+	0x32: {"strtod", []int{BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x33: {"malloc", []int{BIOS_INT}, BIOS_HEX},
+	0x34: {"free", []int{BIOS_HEX}, BIOS_VOID},
+	0x35: {"lsearch", []int{BIOS_HEX, BIOS_HEX, BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_HEX},
+	0x36: {"bsearch", []int{BIOS_HEX, BIOS_HEX, BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_HEX},
+	0x37: {"calloc", []int{BIOS_INT, BIOS_INT}, BIOS_HEX},
+	0x38: {"realloc", []int{BIOS_HEX, BIOS_INT}, BIOS_HEX},
+	0x39: {"InitHeap", []int{BIOS_HEX, BIOS_INT}, BIOS_VOID},
+	0x3a: {"SystemErrorExit", []int{BIOS_INT}, BIOS_VOID},
+	0x3b: {"std_in_getchar", []int{}, BIOS_CHAR},
+	0x3c: {"std_out_putchar", []int{BIOS_CHAR}, BIOS_VOID},
+	0x3d: {"std_in_gets", []int{BIOS_HEX}, BIOS_HEX},
+	0x3e: {"std_out_puts", []int{BIOS_STRING}, BIOS_VOID},
+	0x3f: {"printf", []int{BIOS_FORMAT}, BIOS_INT},
+	0x40: {"SystemErrorUnresolvedException", []int{}, BIOS_VOID},
+	0x41: {"LoadExeHeader", []int{BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x42: {"LoadExeFile", []int{BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x43: {"DoExecute", []int{BIOS_HEX, BIOS_HEX, BIOS_HEX}, BIOS_VOID},
+	0x44: {"FlushCache", []int{}, BIOS_VOID},
+	0x45: {"init_a0_b0_c0_vectors", []int{}, BIOS_VOID},
+	0x46: {"GPU_dw", []int{BIOS_INT, BIOS_INT, BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_VOID},
+	0x47: {"gpu_send_dma", []int{BIOS_INT, BIOS_INT, BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_VOID},
+	0x48: {"SendGP1Command", []int{BIOS_HEX}, BIOS_VOID},
+	0x49: {"GPU_cw", []int{BIOS_HEX}, BIOS_HEX},
+	0x4a: {"GPU_cwp", []int{BIOS_HEX, BIOS_INT}, BIOS_VOID},
+	0x4b: {"send_gpu_linked_list", []int{BIOS_HEX}, BIOS_VOID},
+	0x4c: {"gpu_abort_dma", []int{}, BIOS_VOID},
+	0x4d: {"GetGPUStatus", []int{}, BIOS_HEX},
+	0x4e: {"gpu_sync", []int{}, BIOS_INT},
+	0x51: {"LoadAndExecute", []int{BIOS_STRING, BIOS_HEX, BIOS_HEX}, BIOS_VOID},
+	0x52: {"GetSysSp", []int{}, BIOS_HEX},
+	0x54: {"CdInit", []int{}, BIOS_INT},
+	0x55: {"_bu_init", []int{}, BIOS_VOID},
+	0x56: {"CdRemove", []int{}, BIOS_VOID},
+	0x5b: {"dev_tty_init", []int{}, BIOS_VOID},
+	0x5c: {"dev_tty_open", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX}, BIOS_INT},
+	0x5d: {"dev_tty_in_out", []int{BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x5e: {"dev_tty_ioctl", []int{BIOS_HEX, BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x5f: {"dev_cd_open", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX}, BIOS_INT},
+	0x60: {"dev_cd_read", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x61: {"dev_cd_close", []int{BIOS_HEX}, BIOS_INT},
+	0x62: {"dev_cd_firstfile", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x63: {"dev_cd_nextfile", []int{BIOS_HEX, BIOS_HEX}, BIOS_HEX},
+	0x64: {"dev_cd_chdir", []int{BIOS_HEX, BIOS_STRING}, BIOS_INT},
+	0x65: {"dev_card_open", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX}, BIOS_INT},
+	0x66: {"dev_card_read", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x67: {"dev_card_write", []int{BIOS_HEX, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x68: {"dev_card_close", []int{BIOS_HEX}, BIOS_INT},
+	0x69: {"dev_card_firstfile", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x6a: {"dev_card_nextfile", []int{BIOS_HEX, BIOS_HEX}, BIOS_HEX},
+	0x6b: {"dev_card_erase", []int{BIOS_HEX, BIOS_STRING}, BIOS_INT},
+	0x6c: {"dev_card_undelete", []int{BIOS_HEX, BIOS_STRING}, BIOS_INT},
+	0x6d: {"dev_card_format", []int{BIOS_HEX}, BIOS_INT},
+	0x6e: {"dev_card_rename", []int{BIOS_HEX, BIOS_STRING, BIOS_HEX, BIOS_STRING}, BIOS_INT},
+	0x6f: {"card_clear_error", []int{BIOS_HEX}, BIOS_VOID},
+	0x70: {"_bu_init", []int{}, BIOS_VOID},
+	0x71: {"CdInit", []int{}, BIOS_INT},
+	0x72: {"CdRemove", []int{}, BIOS_VOID},
+	0x78: {"CdAsyncSeekL", []int{BIOS_HEX}, BIOS_INT},
+	0x7c: {"CdAsyncGetStatus", []int{BIOS_HEX}, BIOS_INT},
+	0x7e: {"CdAsyncReadSector", []int{BIOS_INT, BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x81: {"CdAsyncSetMode", []int{BIOS_HEX}, BIOS_INT},
+	0x90: {"CdromIoIrqFunc1", []int{}, BIOS_INT},
+	0x91: {"CdromDmaIrqFunc1", []int{}, BIOS_INT},
+	0x92: {"CdromIoIrqFunc2", []int{}, BIOS_INT},
+	0x93: {"CdromDmaIrqFunc2", []int{}, BIOS_INT},
+	0x94: {"CdromGetInt5errCode", []int{BIOS_HEX, BIOS_HEX}, BIOS_VOID},
+	0x95: {"CdInitSubFunc", []int{}, BIOS_INT},
+	0x96: {"AddCDROMDevice", []int{}, BIOS_VOID},
+	0x97: {"AddMemCardDevice", []int{}, BIOS_VOID},
+	0x98: {"AddDuartTtyDevice", []int{}, BIOS_VOID},
+	0x99: {"AddDummyTtyDevice", []int{}, BIOS_VOID},
+	0x9c: {"SetConf", []int{BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_VOID},
+	0x9d: {"GetConf", []int{BIOS_HEX, BIOS_HEX, BIOS_HEX}, BIOS_VOID},
+	0x9e: {"SetCdromIrqAutoAbort", []int{BIOS_INT, BIOS_INT}, BIOS_VOID},
+	0x9f: {"SetMemSize", []int{BIOS_INT}, BIOS_VOID},
+	0xa0: {"WarmBoot", []int{}, BIOS_VOID},
+	0xa1: {"SystemErrorBootOrDiskFailure", []int{BIOS_CHAR, BIOS_INT}, BIOS_VOID},
+	0xa2: {"EnqueueCdIntr", []int{}, BIOS_VOID},
+	0xa3: {"DequeueCdIntr", []int{}, BIOS_VOID},
+	0xa4: {"CdGetLbn", []int{BIOS_STRING}, BIOS_INT},
+	0xa5: {"CdReadSector", []int{BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_INT},
+	0xa6: {"CdGetStatus", []int{}, BIOS_HEX},
+	0xa7: {"bu_callback_okay", []int{}, BIOS_VOID},
+	0xa8: {"bu_callback_err_write", []int{}, BIOS_VOID},
+	0xa9: {"bu_callback_err_busy", []int{}, BIOS_VOID},
+	0xaa: {"bu_callback_err_eject", []int{}, BIOS_VOID},
+	0xab: {"_card_info", []int{BIOS_HEX}, BIOS_INT},
+	0xac: {"_card_async_load_directory", []int{BIOS_HEX}, BIOS_INT},
+	0xad: {"set_card_auto_format", []int{BIOS_INT}, BIOS_VOID},
+	0xae: {"bu_callback_err_prev_write", []int{}, BIOS_VOID},
+	0xaf: {"card_write_test", []int{BIOS_HEX}, BIOS_INT},
+	0xb2: {"ioabort_raw", []int{BIOS_INT}, BIOS_VOID},
+	0xb4: {"GetSystemInfo", []int{BIOS_INT}, BIOS_HEX},
+}
+
+var BIOS_B_FUNCTIONS = map[uint32]*BIOSFunction{
+	0x00: {"alloc_kernel_memory", []int{BIOS_INT}, BIOS_HEX},
+	0x01: {"free_kernel_memory", []int{BIOS_HEX}, BIOS_VOID},
+	0x02: {"init_timer", []int{BIOS_INT, BIOS_INT, BIOS_HEX}, BIOS_INT},
+	0x03: {"get_timer", []int{BIOS_INT}, BIOS_INT},
+	0x04: {"enable_timer_irq", []int{BIOS_INT}, BIOS_INT},
+	0x05: {"disable_timer_irq", []int{BIOS_INT}, BIOS_INT},
+	0x06: {"restart_timer", []int{BIOS_INT}, BIOS_INT},
+	0x07: {"DeliverEvent", []int{BIOS_EVENT_CLASS, BIOS_EVENT_SPEC}, BIOS_VOID},
+	0x08: {"OpenEvent", []int{BIOS_EVENT_CLASS, BIOS_EVENT_SPEC, BIOS_EVENT_MODE, BIOS_HEX}, BIOS_EVENT},
+	0x09: {"CloseEvent", []int{BIOS_EVENT}, BIOS_INT},
+	0x0a: {"WaitEvent", []int{BIOS_EVENT}, BIOS_INT},
+	0x0b: {"TestEvent", []int{BIOS_EVENT}, BIOS_INT},
+	0x0c: {"EnableEvent", []int{BIOS_EVENT}, BIOS_INT},
+	0x0d: {"DisableEvent", []int{BIOS_EVENT}, BIOS_INT},
+	0x0e: {"OpenThread", []int{BIOS_HEX, BIOS_HEX, BIOS_HEX}, BIOS_HEX},
+	0x0f: {"CloseThread", []int{BIOS_HEX}, BIOS_INT},
+	0x10: {"ChangeThread", []int{BIOS_HEX}, BIOS_INT},
+	0x11: {"jump_to_00000000h", []int{}, BIOS_VOID},
+	0x12: {"InitPad", []int{BIOS_HEX, BIOS_INT, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x13: {"StartPad", []int{}, BIOS_VOID},
+	0x14: {"StopPad", []int{}, BIOS_VOID},
+	0x15: {"OutdatedPadInitAndStart", []int{BIOS_HEX, BIOS_HEX, BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x16: {"OutdatedPadGetButtons", []int{}, BIOS_HEX},
+	0x17: {"ReturnFromException", []int{}, BIOS_VOID},
+	0x18: {"SetDefaultExitFromException", []int{}, BIOS_VOID},
+	0x19: {"SetCustomExitFromException", []int{BIOS_HEX}, BIOS_VOID},
+	0x20: {"UnDeliverEvent", []int{BIOS_EVENT_CLASS, BIOS_EVENT_SPEC}, BIOS_VOID},
+	0x32: {"FileOpen", []int{BIOS_STRING, BIOS_HEX}, BIOS_FD},
+	0x33: {"FileSeek", []int{BIOS_FD, BIOS_INT, BIOS_INT}, BIOS_INT},
+	0x34: {"FileRead", []int{BIOS_FD, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x35: {"FileWrite", []int{BIOS_FD, BIOS_HEX, BIOS_INT}, BIOS_INT},
+	0x36: {"FileClose", []int{BIOS_FD}, BIOS_FD},
+	0x37: {"FileIoctl", []int{BIOS_FD, BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x38: {"exit", []int{BIOS_INT}, BIOS_VOID},
+	0x39: {"FileGetDeviceFlag", []int{BIOS_FD}, BIOS_HEX},
+	0x3a: {"FileGetc", []int{BIOS_FD}, BIOS_CHAR},
+	0x3b: {"FilePutc", []int{BIOS_CHAR, BIOS_FD}, BIOS_INT},
+	0x3c: {"std_in_getchar", []int{}, BIOS_CHAR},
+	0x3d: {"std_out_putchar", []int{BIOS_CHAR}, BIOS_VOID},
+	0x3e: {"std_in_gets", []int{BIOS_HEX}, BIOS_HEX},
+	0x3f: {"std_out_puts", []int{BIOS_STRING}, BIOS_VOID},
+	0x40: {"chdir", []int{BIOS_STRING}, BIOS_INT},
+	0x41: {"FormatDevice", []int{BIOS_STRING}, BIOS_INT},
+	0x42: {"firstfile", []int{BIOS_STRING, BIOS_HEX}, BIOS_HEX},
+	0x43: {"nextfile", []int{BIOS_HEX}, BIOS_HEX},
+	0x44: {"FileRename", []int{BIOS_STRING, BIOS_STRING}, BIOS_INT},
+	0x45: {"FileDelete", []int{BIOS_STRING}, BIOS_INT},
+	0x46: {"FileUndelete", []int{BIOS_STRING}, BIOS_INT},
+	0x47: {"AddDevice", []int{BIOS_HEX}, BIOS_INT},
+	0x48: {"RemoveDevice", []int{BIOS_STRING}, BIOS_INT},
+	0x49: {"PrintInstalledDevices", []int{}, BIOS_VOID},
+	0x4a: {"InitCard", []int{BIOS_INT}, BIOS_VOID},
+	0x4b: {"StartCard", []int{}, BIOS_VOID},
+	0x4c: {"StopCard", []int{}, BIOS_VOID},
+	0x4d: {"_card_info_subfunc", []int{BIOS_HEX}, BIOS_INT},
+	0x4e: {"write_card_sector", []int{BIOS_HEX, BIOS_INT, BIOS_HEX}, BIOS_INT},
+	0x4f: {"read_card_sector", []int{BIOS_HEX, BIOS_INT, BIOS_HEX}, BIOS_INT},
+	0x50: {"allow_new_card", []int{}, BIOS_VOID},
+	0x51: {"Krom2RawAdd", []int{BIOS_HEX}, BIOS_HEX},
+	0x53: {"Krom2Offset", []int{BIOS_HEX}, BIOS_HEX},
+	0x54: {"GetLastError", []int{}, BIOS_INT},
+	0x55: {"GetLastFileError", []int{BIOS_FD}, BIOS_INT},
+	0x56: {"GetC0Table", []int{}, BIOS_HEX},
+	0x57: {"GetB0Table", []int{}, BIOS_HEX},
+	0x58: {"get_bu_callback_port", []int{}, BIOS_INT},
+	0x59: {"testdevice", []int{BIOS_STRING}, BIOS_VOID},
+	0x5b: {"ChangeClearPad", []int{BIOS_INT}, BIOS_VOID},
+	0x5c: {"get_card_status", []int{BIOS_INT}, BIOS_HEX},
+	0x5d: {"wait_card_status", []int{BIOS_INT}, BIOS_HEX},
+}
+
+var BIOS_C_FUNCTIONS = map[uint32]*BIOSFunction{
+	0x00: {"EnqueueTimerAndVblankIrqs", []int{BIOS_INT}, BIOS_VOID},
+	0x01: {"EnqueueSyscallHandler", []int{BIOS_INT}, BIOS_VOID},
+	0x02: {"SysEnqIntRP", []int{BIOS_INT, BIOS_HEX}, BIOS_HEX},
+	0x03: {"SysDeqIntRP", []int{BIOS_INT, BIOS_HEX}, BIOS_HEX},
+	0x04: {"get_free_EvCB_slot", []int{}, BIOS_INT},
+	0x05: {"get_free_TCB_slot", []int{}, BIOS_INT},
+	0x06: {"ExceptionHandler", []int{}, BIOS_VOID},
+	0x07: {"InstallExceptionHandlers", []int{}, BIOS_VOID},
+	0x08: {"SysInitMemory", []int{BIOS_HEX, BIOS_INT}, BIOS_VOID},
+	0x09: {"SysInitKernelVariables", []int{}, BIOS_VOID},
+	0x0a: {"ChangeClearRCnt", []int{BIOS_INT, BIOS_INT}, BIOS_INT},
+	0x0c: {"InitDefInt", []int{BIOS_INT}, BIOS_VOID},
+	0x0d: {"SetIrqAutoAck", []int{BIOS_INT, BIOS_INT}, BIOS_VOID},
+	0x12: {"InstallDevices", []int{BIOS_INT}, BIOS_VOID},
+	0x13: {"FlushStdInOutPut", []int{}, BIOS_VOID},
+	0x15: {"tty_cdevinput", []int{BIOS_HEX, BIOS_CHAR}, BIOS_VOID},
+	0x16: {"tty_cdevscan", []int{}, BIOS_VOID},
+	0x17: {"tty_circgetc", []int{BIOS_HEX}, BIOS_CHAR},
+	0x18: {"tty_circputc", []int{BIOS_CHAR, BIOS_HEX}, BIOS_VOID},
+	0x19: {"ioabort", []int{BIOS_STRING, BIOS_STRING}, BIOS_VOID},
+	0x1a: {"set_card_find_mode", []int{BIOS_INT}, BIOS_VOID},
+	0x1b: {"KernelRedirect", []int{BIOS_INT}, BIOS_VOID},
+	0x1c: {"AdjustA0Table", []int{}, BIOS_VOID},
+	0x1d: {"get_card_find_mode", []int{}, BIOS_INT},
+}
+
+/* by R4; everything from 4 up delivers the syscall event */
+var BIOS_SYSCALLS = map[uint32]*BIOSFunction{
+	0x00: {"NoFunction", []int{}, BIOS_VOID},
+	0x01: {"EnterCriticalSection", []int{}, BIOS_INT},
+	0x02: {"ExitCriticalSection", []int{}, BIOS_VOID},
+	0x03: {"ChangeThreadSubFunction", []int{BIOS_HEX, BIOS_HEX}, BIOS_INT},
+	0x04: {"DeliverEvent(F0000010h,4000h)", []int{}, BIOS_VOID},
+}
+
+/*
+https://psx-spx.consoledev.net/kernelbios/#event-classes
+*/
+var BIOS_EVENT_CLASSES = map[uint32]string{
+	0xf0000001: "VBLANK",
+	0xf0000002: "GPU",
+	0xf0000003: "CDROM",
+	0xf0000004: "DMA",
+	0xf0000005: "RTC0",
+	0xf0000006: "RTC1",
+	0xf0000007: "RTC2",
+	0xf0000008: "CONTROLLER",
+	0xf0000009: "SPU",
+	0xf000000a: "PIO",
+	0xf000000b: "SIO",
+	0xf0000010: "EXCEPTION",
+	0xf0000011: "MEMCARD_LOW",
+	0xf0000012: "MEMCARD_UNUSED1",
+	0xf0000013: "MEMCARD_UNUSED2",
+	0xf2000000: "RCNT0",
+	0xf2000001: "RCNT1",
+	0xf2000002: "RCNT2",
+	0xf2000003: "RCNT3",
+	0xf4000001: "MEMCARD",
+	0xf4000002: "LIBMATH",
+}
+
+/* event specs are bits, except for a few error codes */
+var BIOS_EVENT_SPECS = []string{
+	"COUNTER_ZERO",
+	"INTERRUPTED",
+	"IO_END",
+	"FILE_CLOSED",
+	"COMMAND_ACK",
+	"COMMAND_COMPLETE",
+	"DATA_READY",
+	"DATA_END",
+	"TIMEOUT",
+	"UNKNOWN_COMMAND",
+	"READ_BUFFER_END",
+	"WRITE_BUFFER_END",
+	"GENERAL_INTERRUPT",
+	"NEW_DEVICE",
+	"SYSCALL",
+	"ERROR",
+}
+
+var BIOS_EVENT_SPEC_ERRORS = map[uint32]string{
+	0x0301: "LIBMATH_DOMAIN_ERROR",
+	0x0302: "LIBMATH_RANGE_ERROR",
+	0x8001: "PREVIOUS_WRITE_ERROR",
+}
+
+/* table ("A", "B", "C" or "SYS") and number of a function; nil if it isn't known */
+func LookupBIOSFunction(table string, number uint32) *BIOSFunction {
+	switch table {
+	case "A":
+		return BIOS_A_FUNCTIONS[number]
+	case "B":
+		return BIOS_B_FUNCTIONS[number]
+	case "C":
+		return BIOS_C_FUNCTIONS[number]
+	default:
+		return BIOS_SYSCALLS[uint32(MinOf(int(number), 4))]
+	}
+}
+
+func BIOSFunctionName(table string, number uint32) string {
+	if function := LookupBIOSFunction(table, number); function != nil {
+		return function.Name
+	}
+
+	return "unknown"
+}
+
+/* reads a zero terminated string (at most BIOS_STRING_MAX_LENGTH characters of it) */
+func BIOSString(gostation *GoStation, address uint32) string {
+	var sb strings.Builder
+	for i := 0; i < BIOS_STRING_MAX_LENGTH; i += 1 {
+		ch := gostation.CPU.Peek8(address + uint32(i))
+		if ch == 0 {
+			break
+		}
+		sb.WriteByte(ch)
+	}
+
+	return sb.String()
+}
+
+/* number of arguments a printf format string takes */
+func BIOSFormatArguments(format string) int {
+	count := 0
+	for i := 0; i < len(format)-1; i += 1 {
+		if format[i] != '%' {
+			continue
+		}
+		if format[i+1] == '%' {
+			i += 1
+			continue
+		}
+		count += 1
+	}
+
+	return MinOf(count, 8)
+}
+
+func FormatBIOSValue(gostation *GoStation, kind int, value uint32) string {
+	switch kind {
+	case BIOS_INT:
+		return fmt.Sprintf("%d", int32(value))
+	case BIOS_STRING, BIOS_FORMAT:
+		if value == 0 {
+			return "NULL"
+		}
+		str := BIOSString(gostation, value)
+		if len(str) == BIOS_STRING_MAX_LENGTH {
+			return fmt.Sprintf("%q...", str)
+		}
+		return fmt.Sprintf("%q", str)
+	case BIOS_CHAR:
+		return fmt.Sprintf("%q", rune(value&0xff))
+	case BIOS_FD:
+		return fmt.Sprintf("fd %d", int32(value))
+	case BIOS_EVENT_CLASS:
+		if name, ok := BIOS_EVENT_CLASSES[value]; ok {
+			return name
+		}
+		if value >= 0xff000000 {
+			return fmt.Sprintf("THREAD(%08Xh)", value)
+		}
+		return fmt.Sprintf("%08Xh", value)
+	case BIOS_EVENT_SPEC:
+		if name, ok := BIOS_EVENT_SPEC_ERRORS[value]; ok {
+			return name
+		}
+		var names []string
+		for bit, name := range BIOS_EVENT_SPECS {
+			if TestBit(value, bit) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 || value > 0xffff {
+			return fmt.Sprintf("%04Xh", value)
+		}
+		return strings.Join(names, "|")
+	case BIOS_EVENT_MODE:
+		switch value {
+		case 0x1000:
+			return "EvMdINTR"
+		case 0x2000:
+			return "EvMdNOINTR"
+		}
+		return fmt.Sprintf("%04Xh", value)
+	default:
+		return fmt.Sprintf("%08Xh", value)
+	}
+}
+
+/* the arguments of a call which is about to happen, decoded by their types */
+func FormatBIOSArguments(gostation *GoStation, function *BIOSFunction) string {
+	var args []string
+
+	for i, kind := range function.Args {
+		value := BIOSFunctionArgument(gostation, i)
+		args = append(args, FormatBIOSValue(gostation, kind, value))
+
+		if kind == BIOS_FORMAT && value != 0 {
+			format := BIOSString(gostation, value)
+			for j := 0; j < BIOSFormatArguments(format); j += 1 {
+				args = append(args, FormatBIOSValue(gostation, BIOS_HEX, BIOSFunctionArgument(gostation, i+1+j)))
+			}
+		}
+	}
+
+	return strings.Join(args, ", ")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+BIOS call trace
+
+Every kernel call (and syscall) is written to the trace with its decoded arguments when it is made and
+with its return value (R2) once the cpu gets back to the return address. Calls the kernel makes itself
+are indented below the call they were made from. Some calls never come back (ReturnFromException,
+exit...); they are dropped once a call they were made from returns.
+
+Each line starts with the cpu cycle and the return address of the call (which tells where it was made
+from).
+
+The filter is a comma separated list of terms; a term is a table (A, B, C or SYS), a function (like A3F
+or A(3Fh)) or a function name (case doesn't matter). Calls have to match one of the terms (if there are
+any) and none of the terms which start with a minus, eg. "B,-std_out_putchar" traces the B functions
+except for putchar.
+*/
+const BIOS_TRACE_MAX_DEPTH = 64
+
+type BIOSCall struct {
+	table         string /* A, B, C or SYS */
+	number        uint32
+	function      *BIOSFunction /* nil if it isn't a known function */
+	returnAddress uint32
+	traced        bool /* passed the filter */
+}
+
+type BIOSTrace struct {
+	Core *GoStation
+
+	file    *os.File
+	writer  *bufio.Writer
+	include []string
+	exclude []string
+
+	calls []*BIOSCall /* calls which didn't return yet, innermost last */
+}
+
+/* traces kernel calls into a file ("-" for stdout) */
+func (gostation *GoStation) StartBIOSTrace(path string, filter string) error {
+	var file *os.File
+	var output io.Writer = os.Stdout
+
+	if path != "-" {
+		var err error
+		file, err = os.Create(path)
+		if err != nil {
+			return err
+		}
+		output = file
+	}
+
+	trace := &BIOSTrace{
+		gostation,
+		file,
+		bufio.NewWriter(output),
+		nil,
+		nil,
+		nil,
+	}
+
+	for _, term := range strings.Split(filter, ",") {
+		term = NormalizeBIOSTraceTerm(term)
+		if strings.HasPrefix(term, "-") {
+			trace.exclude = append(trace.exclude, term[1:])
+		} else if term != "" {
+			trace.include = append(trace.include, term)
+		}
+	}
+
+	gostation.biosTrace = trace
+
+	return nil
+}
+
+func (gostation *GoStation) StopBIOSTrace() {
+	trace := gostation.biosTrace
+	if trace == nil {
+		return
+	}
+
+	trace.writer.Flush()
+	if trace.file != nil {
+		trace.file.Close()
+	}
+
+	gostation.biosTrace = nil
+}
+
+/* upper case without spaces, "A(3Fh)" becomes "A3F" */
+func NormalizeBIOSTraceTerm(term string) string {
+	term = strings.ToUpper(strings.TrimSpace(term))
+
+	if strings.HasSuffix(term, "H)") && strings.Contains(term, "(") {
+		term = strings.NewReplacer("(", "", "H)", "").Replace(term)
+	}
+
+	return term
+}
+
+func (trace *BIOSTrace) Matches(call *BIOSCall) bool {
+	terms := []string{
+		call.table,
+		fmt.Sprintf("%s%X", call.table, call.number),
+		fmt.Sprintf("%s%02X", call.table, call.number),
+	}
+	if call.function != nil {
+		terms = append(terms, strings.ToUpper(call.function.Name))
+	}
+
+	contains := func(list []string) bool {
+		for _, term := range terms {
+			for _, other := range list {
+				if term == other {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if len(trace.include) > 0 && !contains(trace.include) {
+		return false
+	}
+
+	return !contains(trace.exclude)
+}
+
+/* checks for calls and returns before the instruction at pc is executed */
+func (trace *BIOSTrace) Step() {
+	cpu := trace.Core.CPU
+	pc := cpu.pc
+
+	trace.CheckReturn(pc)
+
+	switch pc {
+	case 0xa0:
+		trace.Call("A", cpu.reg(9), cpu.reg(31))
+	case 0xb0:
+		trace.Call("B", cpu.reg(9), cpu.reg(31))
+	case 0xc0:
+		trace.Call("C", cpu.reg(9), cpu.reg(31))
+	}
+}
+
+/* called by the syscall instruction; the kernel returns to the instruction after it */
+func (trace *BIOSTrace) SystemCall() {
+	cpu := trace.Core.CPU
+	trace.Call("SYS", cpu.reg(4), cpu.current_pc+4)
+}
+
+func (trace *BIOSTrace) Call(table string, number uint32, returnAddress uint32) {
+	call := &BIOSCall{
+		table,
+		number,
+		LookupBIOSFunction(table, number),
+		returnAddress,
+		false,
+	}
+	call.traced = trace.Matches(call)
+
+	if len(trace.calls) == BIOS_TRACE_MAX_DEPTH {
+		trace.calls = trace.calls[1:]
+	}
+
+	if call.traced {
+		args := ""
+		if call.function != nil {
+			args = FormatBIOSArguments(trace.Core, call.function)
+		}
+		trace.Write(call, fmt.Sprintf("%s(%s)", call.Name(), args))
+	}
+
+	trace.calls = append(trace.calls, call)
+}
+
+func (trace *BIOSTrace) CheckReturn(pc uint32) {
+	for i := len(trace.calls) - 1; i >= 0; i -= 1 {
+		if trace.calls[i].returnAddress != pc {
+			continue
+		}
+
+		// the calls made after it are never coming back
+		for len(trace.calls) > i+1 {
+			trace.Return(false)
+		}
+		trace.Return(true)
+		return
+	}
+}
+
+func (trace *BIOSTrace) Return(returned bool) {
+	call := trace.calls[len(trace.calls)-1]
+	trace.calls = trace.calls[:len(trace.calls)-1]
+
+	if !call.traced {
+		return
+	}
+
+	switch {
+	case !returned:
+		trace.Write(call, fmt.Sprintf("%s didn't return", call.Name()))
+	case call.function == nil:
+		trace.Write(call, fmt.Sprintf("%s returned %08Xh", call.Name(), trace.Core.CPU.reg(2)))
+	case call.function.Return == BIOS_VOID:
+		trace.Write(call, fmt.Sprintf("%s returned", call.Name()))
+	default:
+		trace.Write(call, fmt.Sprintf("%s returned %s", call.Name(), FormatBIOSValue(trace.Core, call.function.Return, trace.Core.CPU.reg(2))))
+	}
+}
+
+func (call *BIOSCall) Name() string {
+	name := "unknown"
+	if call.function != nil {
+		name = call.function.Name
+	}
+
+	return fmt.Sprintf("%s(%02Xh) %s", call.table, call.number, name)
+}
+
+func (trace *BIOSTrace) Write(call *BIOSCall, text string) {
+	indent := strings.Repeat("  ", len(trace.calls))
+
+	fmt.Fprintf(trace.writer, "%12d %08x %s%s\n", trace.Core.Scheduler.Now(), call.returnAddress, indent, text)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBIOSTraceTerm(t *testing.T) {
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{"a", "A"},
+		{" sys ", "SYS"},
+		{"A(3Fh)", "A3F"},
+		{"b(0bh)", "B0B"},
+		{"a3f", "A3F"},
+		{"-C(13h)", "-C13"},
+		{"-std_out_putchar", "-STD_OUT_PUTCHAR"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if term := NormalizeBIOSTraceTerm(test.term); term != test.expected {
+			t.Errorf("%q normalizes to %q, expected %q", test.term, term, test.expected)
+		}
+	}
+}
+
+/*
+Program which calls A(04h) FileClose(3), B(0Bh) TestEvent(3) and the syscall EnterCriticalSection. The
+"kernel" in ram returns -1 from A functions; B functions call C(13h), which doesn't come back to them but
+returns 1 to the caller of B directly, and the exception handler returns 1 from syscalls.
+*/
+const biosTraceProgramEnd = 0xbfc00030
+
+func NewBIOSTraceTestGoStation(t *testing.T) *GoStation {
+	gostation := NewTestGoStation(t,
+		AsmORI(4, 0, 3),
+		AsmORI(9, 0, 0x04),
+		AsmORI(8, 0, 0xa0),
+		AsmJALR(31, 8), // returns to bfc00014h
+		AsmNOP(),
+		AsmORI(9, 0, 0x0b),
+		AsmORI(8, 0, 0xb0),
+		AsmJALR(31, 8), // returns to bfc00024h
+		AsmNOP(),
+		AsmORI(4, 0, 1),
+		AsmSYSCALL(), // returns to bfc0002ch
+		AsmNOP(),
+		AsmBEQ(0, 0, -1),
+		AsmNOP(),
+	)
+
+	kernel := map[uint32][]uint32{
+		0x080: {AsmORI(2, 0, 1), AsmMFC0(26, 14), AsmNOP(), AsmADDIU(26, 26, 4), AsmJR(26), AsmRFE()},
+		0x0a0: {AsmADDIU(2, 0, -1), AsmJR(31), AsmNOP()},
+		0x0b0: {AsmORI(8, 0, 0x200), AsmJR(8), AsmNOP()},
+		0x0c0: {AsmORI(2, 0, 1), AsmJR(27), AsmNOP()},
+		0x200: {AsmADDU(27, 31, 0), AsmORI(9, 0, 0x13), AsmORI(8, 0, 0xc0), AsmJALR(31, 8), AsmNOP(), AsmJR(27), AsmNOP()},
+	}
+	for address, code := range kernel {
+		for i, instruction := range code {
+			gostation.Bus.Ram.Write32(address+uint32(i)*4, instruction)
+		}
+	}
+
+	return gostation
+}
+
+/* the lines of the trace without the cycle they were written at */
+func RunBIOSTrace(t *testing.T, filter string) []string {
+	gostation := NewBIOSTraceTestGoStation(t)
+	path := filepath.Join(t.TempDir(), "trace.txt")
+
+	if err := gostation.StartBIOSTrace(path, filter); err != nil {
+		t.Fatal(err)
+	}
+	RunUntil(t, gostation, biosTraceProgramEnd)
+	gostation.StopBIOSTrace()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if line != "" {
+			lines = append(lines, line[13:])
+		}
+	}
+
+	return lines
+}
+
+func TestBIOSTrace(t *testing.T) {
+	expected := []string{
+		"bfc00014 A(04h) FileClose(fd 3)",
+		"bfc00014 A(04h) FileClose returned -1",
+		"bfc00024 B(0Bh) TestEvent(00000003h)",
+		"00000214   C(13h) FlushStdInOutPut()",
+		"00000214   C(13h) FlushStdInOutPut didn't return",
+		"bfc00024 B(0Bh) TestEvent returned 1",
+		"bfc0002c SYS(01h) EnterCriticalSection()",
+		"bfc0002c SYS(01h) EnterCriticalSection returned 1",
+	}
+
+	lines := RunBIOSTrace(t, "")
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("trace is\n%s\nexpected\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBIOSTraceFilter(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected []string /* calls in the trace */
+	}{
+		{"A", []string{"A(04h)"}},
+		{"b,sys", []string{"B(0Bh)", "SYS(01h)"}},
+		{"A(04h),c13", []string{"A(04h)", "C(13h)"}},
+		{"fileclose, EnterCriticalSection", []string{"A(04h)", "SYS(01h)"}},
+		{"-B", []string{"A(04h)", "C(13h)", "SYS(01h)"}},
+		{"-A(04h),-flushstdinoutput", []string{"B(0Bh)", "SYS(01h)"}},
+		{"B,C,-TestEvent", []string{"C(13h)"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			calls := []string{}
+			for _, line := range RunBIOSTrace(t, test.filter) {
+				// every call has a line when it is made and one when it returns
+				if strings.HasSuffix(line, ")") {
+					calls = append(calls, strings.Fields(line)[1])
+				}
+			}
+
+			if strings.Join(calls, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("traced %v, expected %v", calls, test.expected)
+			}
+		})
+	}
+}
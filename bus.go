@@ -1,358 +1,311 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"os"
-)
-
-/*
-https://psx-spx.consoledev.net/memorymap/
-
-	KUSEG     KSEG0     KSEG1
-	00000000h 80000000h A0000000h  2048K  Main RAM (first 64K reserved for BIOS)
-	1F000000h 9F000000h BF000000h  8192K  Expansion Region 1 (ROM/RAM)
-	1F800000h 9F800000h    --      1K     Scratchpad (D-Cache used as Fast RAM)
-	1F801000h 9F801000h BF801000h  8K     I/O Ports
-	1F802000h 9F802000h BF802000h  8K     Expansion Region 2 (I/O Ports)
-	1FA00000h 9FA00000h BFA00000h  2048K  Expansion Region 3 (SRAM BIOS region for DTL cards)
-	1FC00000h 9FC00000h BFC00000h  512K   BIOS ROM (Kernel) (4096K max)
-	      FFFE0000h (in KSEG2)     0.5K   Internal CPU control registers (Cache Control)
-
-Notes:
-- KUSEG is a physical memory region and KSEG0, KSEG1 and KSEG2 are virtual memory mirrors.
-- Notice that all addresses in KUSEG are smaller than KSEG0, etc.
-
-Size of KUSEG: 80000000h bytes
-Size of KSEG0: 20000000h bytes (mirrored to the first 20000000h bytes in KUSEG)
-Size of KSEG1: 20000000h bytes (mirrored to the first 20000000h bytes in KUSEG)
-Size of KSEG2: 40000000h bytes
-*/
-type Bus struct {
-	Core           *GoStation
-	Ram            *Memory
-	Bios           *Memory
-	ScratchPad     *Memory
-	MemoryControl1 *MemoryControl1
-	SPU            *Memory
-	Peripheral     *Memory /* TODO */
-	Timer          *Memory /* TODO */
-	Expansion1     *Memory
-	Expansion2     *Memory /* TODO implement debug uart */
-}
-
-func NewBus(core *GoStation, pathToBios string) *Bus {
-	bios, err := os.ReadFile(pathToBios)
-	if err != nil {
-		log.Fatal("Unable to read BIOS: ", err)
-	}
-
-	return &Bus{
-		core,
-		NewMemory(make([]uint8, 2*1024*1024), 0x00000000, 2*1024*1024),
-		NewMemory(bios, 0x1fc00000, 1024*512),
-		NewMemory(make([]uint8, 0x400), 0x1f800000, 0x400),
-		NewMemoryControl1(),
-		NewMemory(make([]uint8, 640), 0x1f801c00, 640),
-		NewMemory(make([]uint8, 32), 0x1f801040, 32),
-		NewMemory(make([]uint8, 3*16), 0x1f801100, 3*16),
-		NewMemory(make([]uint8, 1024*512), 0x1f000000, 1024*512),
-		NewMemory(make([]uint8, 128), 0x1f802000, 128),
-	}
-}
-
-func (bus *Bus) Read8(address uint32) uint8 {
-	if bus.Bios.Contains(address) {
-		return bus.Bios.Read8(address)
-	}
-
-	if bus.Ram.Contains(address) {
-		return bus.Ram.Read8(address)
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		return bus.ScratchPad.Read8(address)
-	}
-
-	if bus.SPU.Contains(address) {
-		return bus.SPU.Read8(address)
-	}
-
-	if bus.Peripheral.Contains(address) {
-		return bus.Peripheral.Read8(address)
-	}
-
-	if bus.Core.CDROM.Contains(address) {
-		return bus.Core.CDROM.Read8(address)
-	}
-
-	if bus.Expansion1.Contains(address) {
-		return bus.Expansion1.Read8(address)
-	}
-
-	if bus.Expansion2.Contains(address) {
-		return bus.Expansion2.Read8(address)
-	}
-
-	panic(fmt.Sprintf("[Bus::Read8] Invalid address: %x", address))
-}
-
-func (bus *Bus) Read16(address uint32) uint16 {
-	if bus.Bios.Contains(address) {
-		return bus.Bios.Read16(address)
-	}
-
-	if bus.Ram.Contains(address) {
-		return bus.Ram.Read16(address)
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		return bus.ScratchPad.Read16(address)
-	}
-
-	if bus.SPU.Contains(address) {
-		return bus.SPU.Read16(address)
-	}
-
-	if bus.Peripheral.Contains(address) {
-		if address == 0x1f801044 {
-			return 3
-		}
-
-		return bus.Peripheral.Read16(address)
-	}
-
-	if bus.Timer.Contains(address) {
-		if address == 0x1f801120 {
-			return 0x000016b0
-		}
-
-		return bus.Timer.Read16(address)
-	}
-
-	if bus.Expansion1.Contains(address) {
-		return bus.Expansion1.Read16(address)
-	}
-
-	if bus.Expansion2.Contains(address) {
-		return bus.Expansion2.Read16(address)
-	}
-
-	if bus.Core.Interrupts.Contains(address) {
-		return bus.Core.Interrupts.Read16(address)
-	}
-
-	panic(fmt.Sprintf("[Bus::Read16] Invalid address: %x", address))
-}
-
-func (bus *Bus) Read32(address uint32) uint32 {
-	if bus.Bios.Contains(address) {
-		return bus.Bios.Read32(address)
-	}
-
-	if bus.Ram.Contains(address) {
-		return bus.Ram.Read32(address)
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		return bus.ScratchPad.Read32(address)
-	}
-
-	if bus.MemoryControl1.Contains(address) {
-		return bus.MemoryControl1.Read32(address)
-	}
-
-	if bus.SPU.Contains(address) {
-		return bus.SPU.Read32(address)
-	}
-
-	if bus.Peripheral.Contains(address) {
-		return bus.Peripheral.Read32(address)
-	}
-
-	if bus.Timer.Contains(address) {
-		return bus.Timer.Read32(address)
-	}
-
-	if bus.Core.DMA.Contains(address) {
-		return bus.Core.DMA.Read32(address)
-	}
-
-	if bus.Core.GPU.Contains(address) {
-		return bus.Core.GPU.Read32(address)
-	}
-
-	if bus.Expansion1.Contains(address) {
-		return bus.Expansion1.Read32(address)
-	}
-
-	if bus.Expansion2.Contains(address) {
-		return bus.Expansion2.Read32(address)
-	}
-
-	if bus.Core.Interrupts.Contains(address) {
-		return bus.Core.Interrupts.Read32(address)
-	}
-
-	panic(fmt.Sprintf("[Bus::Read32] Invalid address: %x", address))
-}
-
-/*
-TODO what to do if data width changed? e.g. writing 32 bit val then suddenly 16 bit val. treat as 32 bit and pad zeroes?
-*/
-
-func (bus *Bus) Write8(address uint32, data uint8) {
-	if bus.Ram.Contains(address) {
-		bus.Ram.Write8(address, data)
-		return
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		bus.ScratchPad.Write8(address, data)
-		return
-	}
-
-	if bus.SPU.Contains(address) {
-		bus.SPU.Write8(address, data)
-		return
-	}
-
-	if bus.Peripheral.Contains(address) {
-		bus.Peripheral.Write8(address, data)
-		return
-	}
-
-	if bus.Core.CDROM.Contains(address) {
-		bus.Core.CDROM.Write8(address, data)
-		return
-	}
-
-	if bus.Expansion1.Contains(address) {
-		bus.Expansion1.Write8(address, data)
-		return
-	}
-
-	if bus.Expansion2.Contains(address) {
-		if address == 0x1F802041 {
-			fmt.Printf("BIOS Trace: %x\n", data)
-		}
-
-		bus.Expansion2.Write8(address, data)
-		return
-	}
-
-	panic(fmt.Sprintf("[Bus::Write8] Can't write data %x into this address: %x", data, address))
-}
-
-func (bus *Bus) Write16(address uint32, data uint16) {
-	if bus.Ram.Contains(address) {
-		bus.Ram.Write16(address, data)
-		return
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		bus.ScratchPad.Write16(address, data)
-		return
-	}
-
-	if bus.SPU.Contains(address) {
-		bus.SPU.Write16(address, data)
-		return
-	}
-
-	if bus.Peripheral.Contains(address) {
-		bus.Peripheral.Write16(address, data)
-		return
-	}
-
-	if bus.Timer.Contains(address) {
-		bus.Timer.Write16(address, data)
-		return
-	}
-
-	if bus.Expansion1.Contains(address) {
-		bus.Expansion1.Write16(address, data)
-		return
-	}
-
-	if bus.Expansion2.Contains(address) {
-		bus.Expansion2.Write16(address, data)
-		return
-	}
-
-	if bus.Core.Interrupts.Contains(address) {
-		bus.Core.Interrupts.Write16(address, data)
-		return
-	}
-
-	panic(fmt.Sprintf("[Bus::Write16] Can't write data %x into this address: %x", data, address))
-}
-
-func (bus *Bus) Write32(address uint32, data uint32) {
-	if bus.Ram.Contains(address) {
-		bus.Ram.Write32(address, data)
-		return
-	}
-
-	if bus.ScratchPad.Contains(address) {
-		bus.ScratchPad.Write32(address, data)
-		return
-	}
-
-	if bus.MemoryControl1.Contains(address) {
-		bus.MemoryControl1.Write32(address, data)
-		return
-	}
-
-	if bus.SPU.Contains(address) {
-		bus.SPU.Write32(address, data)
-		return
-	}
-
-	if bus.Peripheral.Contains(address) {
-		bus.Peripheral.Write32(address, data)
-		return
-	}
-
-	if bus.Timer.Contains(address) {
-		bus.Timer.Write32(address, data)
-		return
-	}
-
-	if bus.Core.DMA.Contains(address) {
-		bus.Core.DMA.Write32(address, data)
-		return
-	}
-
-	if bus.Core.GPU.Contains(address) {
-		bus.Core.GPU.Write32(address, data)
-		return
-	}
-
-	if bus.Expansion1.Contains(address) {
-		bus.Expansion1.Write32(address, data)
-		return
-	}
-
-	if bus.Expansion2.Contains(address) {
-		bus.Expansion2.Write32(address, data)
-		return
-	}
-
-	if bus.Core.Interrupts.Contains(address) {
-		bus.Core.Interrupts.Write32(address, data)
-		return
-	}
-
-	if address == 0x1f801060 {
-		// Ignore the RAM_SIZE register for now
-		return
-	}
-
-	if address == 0xfffe0130 {
-		// Ignore the cache kontrol register for now
-		return
-	}
-
-	panic(fmt.Sprintf("[Bus::Write32] Can't write data %x into this address: %x", data, address))
-}
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"os"
+)
+
+/*
+https://psx-spx.consoledev.net/memorymap/
+
+	KUSEG     KSEG0     KSEG1
+	00000000h 80000000h A0000000h  2048K  Main RAM (first 64K reserved for BIOS)
+	1F000000h 9F000000h BF000000h  8192K  Expansion Region 1 (ROM/RAM)
+	1F800000h 9F800000h    --      1K     Scratchpad (D-Cache used as Fast RAM)
+	1F801000h 9F801000h BF801000h  8K     I/O Ports
+	1F802000h 9F802000h BF802000h  8K     Expansion Region 2 (I/O Ports)
+	1FA00000h 9FA00000h BFA00000h  2048K  Expansion Region 3 (SRAM BIOS region for DTL cards)
+	1FC00000h 9FC00000h BFC00000h  512K   BIOS ROM (Kernel) (4096K max)
+	      FFFE0000h (in KSEG2)     0.5K   Internal CPU control registers (Cache Control)
+
+Notes:
+- KUSEG is a physical memory region and KSEG0, KSEG1 and KSEG2 are virtual memory mirrors.
+- Notice that all addresses in KUSEG are smaller than KSEG0, etc.
+
+Size of KUSEG: 80000000h bytes
+Size of KSEG0: 20000000h bytes (mirrored to the first 20000000h bytes in KUSEG)
+Size of KSEG1: 20000000h bytes (mirrored to the first 20000000h bytes in KUSEG)
+Size of KSEG2: 40000000h bytes
+*/
+type Bus struct {
+	Core           *GoStation
+	Ram            *Memory
+	Bios           *Memory
+	ScratchPad     *Memory /* only accessed by the cpu */
+	MemoryControl1 *MemoryControl1
+	SPU            *Memory
+	Peripheral     *Memory /* TODO */
+	Expansion1     *Memory
+	Expansion2     *Memory /* TODO implement debug uart */
+
+	readPages  []*[BUS_PAGE_SIZE]uint8 /* nil for addresses which aren't plain memory */
+	writePages []*[BUS_PAGE_SIZE]uint8
+	io         [IO_SLOTS]*IOHandler
+	ramSize    uint32 /* 1F801060h */
+
+	policy    int  /* BUS_POLICY_* */
+	fault     bool /* set by accesses which raise a bus error */
+	unhandled map[UnhandledAccessKey]*UnhandledAccess
+
+	cycles uint32 /* cycles taken by cpu accesses which weren't consumed by GoStation::Step yet */
+
+	blocks *BlockCache /* decoded code which has to be thrown away when ram gets written (may be nil) */
+}
+
+/*
+Access timing (on top of the cycle of the instruction)
+
+Main RAM reads take a few cycles. BIOS ROM, the CDROM, the SPU and the expansion regions take as long as
+their Delay/Size registers in MemoryControl1 say.
+
+Writes to RAM and the I/O ports are free: the cpu puts stores into its 4 entry write buffer and carries on
+while the buffer drains to memory in the background, so a store only stalls the cpu if the buffer is full
+(or a read has to wait for it). The buffer isn't emulated since games rarely store often enough in a row to
+fill it, so stores aren't charged anything.
+*/
+const (
+	RAM_READ_CYCLES = 5
+	IO_READ_CYCLES  = 2
+)
+
+func NewBus(core *GoStation, pathToBios string) *Bus {
+	bios, err := os.ReadFile(pathToBios)
+	if err != nil {
+		log.Fatal("Unable to read BIOS: ", err)
+	}
+
+	bus := &Bus{
+		core,
+		NewMemory(make([]uint8, MAIN_RAM_SIZE), 0x00000000, MAIN_RAM_SIZE),
+		NewMemory(bios, 0x1fc00000, 1024*512),
+		NewMemory(make([]uint8, SCRATCHPAD_SIZE), SCRATCHPAD_OFFSET, SCRATCHPAD_SIZE),
+		NewMemoryControl1(),
+		NewMemory(make([]uint8, 640), 0x1f801c00, 640),
+		NewMemory(make([]uint8, 32), 0x1f801040, 32),
+		NewMemory(make([]uint8, 1024*512), 0x1f000000, 1024*512),
+		NewMemory(make([]uint8, 128), 0x1f802000, 128),
+		make([]*[BUS_PAGE_SIZE]uint8, BUS_PAGES),
+		make([]*[BUS_PAGE_SIZE]uint8, BUS_PAGES),
+		[IO_SLOTS]*IOHandler{},
+		0x00000b88,
+		BUS_POLICY_LENIENT,
+		false,
+		make(map[UnhandledAccessKey]*UnhandledAccess),
+		0,
+		nil,
+	}
+
+	bus.MapMemory()
+
+	return bus
+}
+
+/* charges a cpu access of the given width (ACCESS_BYTE, ACCESS_HALFWORD or ACCESS_WORD) to a physical address */
+func (bus *Bus) Charge(address uint32, width int, write bool) {
+	bus.cycles += bus.AccessCycles(address, width, write)
+}
+
+/* returns and resets the cycles charged since the last call */
+func (bus *Bus) TakeCycles() uint32 {
+	cycles := bus.cycles
+	bus.cycles = 0
+	return cycles
+}
+
+func (bus *Bus) AccessCycles(address uint32, width int, write bool) uint32 {
+	mc1 := bus.MemoryControl1
+
+	switch {
+	case address < 0x00800000: // main ram and its mirrors
+		if write {
+			// absorbed by the write buffer
+			return 0
+		}
+		return RAM_READ_CYCLES
+	case address >= 0x1f000000 && address < 0x1f800000:
+		return mc1.AccessTime(MC1_REGION_EXP1, width, write)
+	case address >= CDROM_OFFSET && address < CDROM_OFFSET+CDROM_SIZE:
+		return mc1.AccessTime(MC1_REGION_CDROM, width, write)
+	case address >= 0x1f801c00 && address < 0x1f802000:
+		return mc1.AccessTime(MC1_REGION_SPU, width, write)
+	case address >= 0x1f801000 && address < 0x1f802000: // other i/o ports
+		if write {
+			return 0
+		}
+		return IO_READ_CYCLES
+	case address >= 0x1f802000 && address < 0x1f804000:
+		return mc1.AccessTime(MC1_REGION_EXP2, width, write)
+	case address >= 0x1fa00000 && address < 0x1fc00000:
+		return mc1.AccessTime(MC1_REGION_EXP3, width, write)
+	case address >= 0x1fc00000 && address < 0x1fc80000:
+		return mc1.AccessTime(MC1_REGION_BIOS, width, write)
+	}
+
+	return 0
+}
+
+func (bus *Bus) Read8(address uint32) uint8 {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.readPages[address>>BUS_PAGE_BITS]; page != nil {
+			return page[address&BUS_PAGE_MASK]
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Read8 != nil {
+		return handler.Read8(address)
+	}
+
+	return uint8(bus.Unmapped(address, ACCESS_BYTE, false, 0))
+}
+
+func (bus *Bus) Read16(address uint32) uint16 {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.readPages[address>>BUS_PAGE_BITS]; page != nil {
+			return binary.LittleEndian.Uint16(page[address&BUS_PAGE_MASK:])
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Read16 != nil {
+		return handler.Read16(address)
+	}
+
+	return uint16(bus.Unmapped(address, ACCESS_HALFWORD, false, 0))
+}
+
+func (bus *Bus) Read32(address uint32) uint32 {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.readPages[address>>BUS_PAGE_BITS]; page != nil {
+			return binary.LittleEndian.Uint32(page[address&BUS_PAGE_MASK:])
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Read32 != nil {
+		return handler.Read32(address)
+	}
+
+	if bus.Core.CPU.icache.Contains(address) {
+		return bus.Core.CPU.icache.Read32(address)
+	}
+
+	return bus.Unmapped(address, ACCESS_WORD, false, 0)
+}
+
+/*
+Reads for the debugger, the kernel call hooks and the block compiler: only plain memory (ram, BIOS ROM and expansion region 1)
+is read, everything else reads as zero. Unlike Read8/Read32 they never touch devices, raise bus errors or
+log, so the emulated console can't tell they happened.
+*/
+func (bus *Bus) Peek8(address uint32) uint8 {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.readPages[address>>BUS_PAGE_BITS]; page != nil {
+			return page[address&BUS_PAGE_MASK]
+		}
+	}
+
+	return 0
+}
+
+func (bus *Bus) Peek32(address uint32) uint32 {
+	address &^= 3
+
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.readPages[address>>BUS_PAGE_BITS]; page != nil {
+			return binary.LittleEndian.Uint32(page[address&BUS_PAGE_MASK:])
+		}
+	}
+
+	return 0
+}
+
+/* whether the address is plain memory, which is all Peek8/Peek32 read */
+func (bus *Bus) Peekable(address uint32) bool {
+	return address < BUS_PAGES<<BUS_PAGE_BITS && bus.readPages[address>>BUS_PAGE_BITS] != nil
+}
+
+/*
+Word accesses of the dma controller; it's wired to the 2MB of ram directly (address is masked to it) so
+the memory window of RAM_SIZE doesn't apply and there are no bus errors
+*/
+func (bus *Bus) ReadRAM32(address uint32) uint32 {
+	return binary.LittleEndian.Uint32(bus.Ram.Data[address&(MAIN_RAM_SIZE-4):])
+}
+
+func (bus *Bus) WriteRAM32(address uint32, data uint32) {
+	address &= MAIN_RAM_SIZE - 4
+
+	bus.Core.PGXP.TrackStore(address, data)
+	if bus.blocks != nil {
+		bus.blocks.InvalidateAddress(address)
+	}
+	binary.LittleEndian.PutUint32(bus.Ram.Data[address:], data)
+}
+
+/*
+TODO what to do if data width changed? e.g. writing 32 bit val then suddenly 16 bit val. treat as 32 bit and pad zeroes?
+*/
+
+func (bus *Bus) Write8(address uint32, data uint8) {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.writePages[address>>BUS_PAGE_BITS]; page != nil {
+			bus.Core.PGXP.Invalidate(PGXPAddress(address))
+			if bus.blocks != nil && address < RAM_WINDOW_SIZE {
+				bus.blocks.InvalidateAddress(address)
+			}
+			page[address&BUS_PAGE_MASK] = data
+			return
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Write8 != nil {
+		handler.Write8(address, data)
+		return
+	}
+
+	bus.Unmapped(address, ACCESS_BYTE, true, uint32(data))
+}
+
+func (bus *Bus) Write16(address uint32, data uint16) {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.writePages[address>>BUS_PAGE_BITS]; page != nil {
+			bus.Core.PGXP.Invalidate(PGXPAddress(address))
+			if bus.blocks != nil && address < RAM_WINDOW_SIZE {
+				bus.blocks.InvalidateAddress(address)
+			}
+			binary.LittleEndian.PutUint16(page[address&BUS_PAGE_MASK:], data)
+			return
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Write16 != nil {
+		handler.Write16(address, data)
+		return
+	}
+
+	bus.Unmapped(address, ACCESS_HALFWORD, true, uint32(data))
+}
+
+func (bus *Bus) Write32(address uint32, data uint32) {
+	if address < BUS_PAGES<<BUS_PAGE_BITS {
+		if page := bus.writePages[address>>BUS_PAGE_BITS]; page != nil {
+			bus.Core.PGXP.TrackStore(PGXPAddress(address), data)
+			if bus.blocks != nil && address < RAM_WINDOW_SIZE {
+				bus.blocks.InvalidateAddress(address)
+			}
+			binary.LittleEndian.PutUint32(page[address&BUS_PAGE_MASK:], data)
+			return
+		}
+	}
+
+	if handler := bus.IOHandler(address); handler != nil && handler.Write32 != nil {
+		handler.Write32(address, data)
+		return
+	}
+
+	if bus.Core.CPU.icache.Contains(address) {
+		bus.Core.CPU.icache.Write32(address, data)
+		return
+	}
+
+	bus.Unmapped(address, ACCESS_WORD, true, data)
+}
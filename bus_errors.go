@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+/*
+Unmapped accesses
+
+What happens to an access nothing on the bus answers depends on where it goes:
+
+  - KSEG2 (except the cache control register), the physical addresses above 1FFFFFFFh, the locked part
+    of the ram window (see RAM_SIZE) and the scratchpad (which only the cpu can access, see
+    CPU::ScratchPadAddress) raise a bus error; IBE for instruction fetches, DBE for loads/stores
+  - the expansion regions, the unused parts of the memory map and BIOS ROM (for writes) float: reads
+    return all ones and writes are ignored
+  - i/o ports (or access widths) the emulator doesn't implement read as zero and ignore writes
+
+In the lenient policy the above is emulated and every access is recorded in the unhandled access
+registry (and logged the first time). The strict policy panics instead which is handy for finding out
+what a game needs while developing.
+*/
+const (
+	BUS_POLICY_LENIENT = iota
+	BUS_POLICY_STRICT
+)
+
+const (
+	UNMAPPED_UNIMPLEMENTED = iota
+	UNMAPPED_OPEN_BUS
+	UNMAPPED_BUS_ERROR
+)
+
+type UnhandledAccess struct {
+	Address uint32
+	Width   int /* ACCESS_BYTE, ACCESS_HALFWORD or ACCESS_WORD */
+	Write   bool
+	Kind    int /* UNMAPPED_* */
+	Count   int
+}
+
+type UnhandledAccessKey struct {
+	address uint32
+	width   int
+	write   bool
+}
+
+func (bus *Bus) SetPolicy(policy int) {
+	bus.policy = policy
+}
+
+/* whether the last access raised a bus error; resets the flag */
+func (bus *Bus) TakeFault() bool {
+	fault := bus.fault
+	bus.fault = false
+	return fault
+}
+
+func (bus *Bus) ClassifyUnmapped(address uint32) int {
+	switch {
+	case address >= BUS_PAGES<<BUS_PAGE_BITS:
+		return UNMAPPED_BUS_ERROR
+	case address < RAM_WINDOW_SIZE:
+		return UNMAPPED_BUS_ERROR
+	case address-SCRATCHPAD_OFFSET < SCRATCHPAD_SIZE:
+		return UNMAPPED_BUS_ERROR
+	case address >= IO_OFFSET && address < 0x1f802000:
+		return UNMAPPED_UNIMPLEMENTED
+	}
+
+	return UNMAPPED_OPEN_BUS
+}
+
+/*
+Handles an access which no page or device took; returns the value a read gets (truncated by the caller)
+*/
+func (bus *Bus) Unmapped(address uint32, width int, write bool, data uint32) uint32 {
+	kind := bus.ClassifyUnmapped(address)
+	name := []string{"Read8", "Read16", "Read32"}[width]
+	if write {
+		name = []string{"Write8", "Write16", "Write32"}[width]
+	}
+
+	if bus.policy == BUS_POLICY_STRICT {
+		if write {
+			panic(fmt.Sprintf("[Bus::%s] Can't write data %x into this address: %x", name, data, address))
+		}
+		panic(fmt.Sprintf("[Bus::%s] Invalid address: %x", name, address))
+	}
+
+	key := UnhandledAccessKey{address, width, write}
+	access, ok := bus.unhandled[key]
+	if !ok {
+		access = &UnhandledAccess{address, width, write, kind, 0}
+		bus.unhandled[key] = access
+
+		fmt.Printf("[Bus::%s] WARNING: unhandled access to %x (%s)\n", name, address, access.KindName())
+	}
+	access.Count += 1
+
+	switch kind {
+	case UNMAPPED_BUS_ERROR:
+		bus.fault = true
+		return 0
+	case UNMAPPED_OPEN_BUS:
+		return 0xffffffff
+	default:
+		return 0
+	}
+}
+
+func (access *UnhandledAccess) KindName() string {
+	return []string{"unimplemented", "open bus", "bus error"}[access.Kind]
+}
+
+/* accesses recorded by the lenient policy, by address */
+func (bus *Bus) UnhandledAccesses() []*UnhandledAccess {
+	accesses := make([]*UnhandledAccess, 0, len(bus.unhandled))
+	for _, access := range bus.unhandled {
+		accesses = append(accesses, access)
+	}
+
+	sort.Slice(accesses, func(i, j int) bool {
+		a, b := accesses[i], accesses[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Write != b.Write {
+			return !a.Write
+		}
+		return a.Width < b.Width
+	})
+
+	return accesses
+}
+
+func (bus *Bus) DumpUnhandledAccesses(writer io.Writer) {
+	for _, access := range bus.UnhandledAccesses() {
+		direction := "read"
+		if access.Write {
+			direction = "write"
+		}
+
+		fmt.Fprintf(writer, "%08x %-5s %2d bit  %-13s %d times\n", access.Address, direction, 8<<access.Width, access.KindName(), access.Count)
+	}
+}
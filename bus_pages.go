@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+)
+
+/*
+Bus dispatch
+
+Main RAM, BIOS ROM and expansion region 1 are plain memory; they are looked up in a page table (1KiB
+pages, the size of the scratchpad) which points straight into their buffers. BIOS ROM only has read
+pages. The scratchpad isn't on the bus, the cpu accesses it directly (see CPU::ScratchPadAddress).
+
+Everything else in 1F801000h..1F803FFFh (i/o ports and expansion region 2) goes thru the i/o handler
+table which has an entry per word. A handler only has the access widths the device supports; accesses
+with other widths (or to unmapped addresses) are invalid.
+*/
+const (
+	BUS_PAGE_BITS = 10
+	BUS_PAGE_SIZE = 1 << BUS_PAGE_BITS
+	BUS_PAGE_MASK = BUS_PAGE_SIZE - 1
+	BUS_PAGES     = 0x20000000 >> BUS_PAGE_BITS /* the physical address space, cpu addresses are masked to it */
+
+	IO_OFFSET = 0x1f801000
+	IO_SIZE   = 0x3000
+	IO_SLOTS  = IO_SIZE / 4
+)
+
+/*
+https://psx-spx.consoledev.net/memorycontrol/#1f801060h-ram_size-rw
+
+	0-2   Unknown (no effect)
+	3     Crashes when zero (except PU-7 and EARLY-PU-8, which <do> set bit3=0)
+	4-6   Unknown (no effect)
+	7     Delay on simultaneous CODE+DATA fetch from RAM (0=None, 1=One Cycle)
+	8     Unknown (no effect) (should be set for 8MB, cleared for 2MB)
+	9-11  Define 8MB Memory Window (first 8MB of KUSEG) (0-7)
+	12-15 Unknown (no effect)
+	16-31 Unknown (Garbage)
+
+Memory windows:
+
+	0 = 1MB Memory + 7MB Locked
+	1 = 4MB Memory + 4MB Locked
+	2 = 1MB Memory + 1MB HighZ + 6MB Locked
+	3 = 4MB Memory + 4MB HighZ
+	4 = 2MB Memory + 6MB Locked
+	5 = 8MB Memory               ;<--- default by BIOS init
+	6 = 2MB Memory + 2MB HighZ + 4MB Locked
+	7 = 8MB Memory
+
+The 2MB of ram are mirrored thru the memory part of the window; HighZ and Locked areas aren't mapped.
+*/
+const (
+	RAM_SIZE_REGISTER = 0x1f801060
+	MAIN_RAM_SIZE     = 2 * 1024 * 1024
+	RAM_WINDOW_SIZE   = 8 * 1024 * 1024
+)
+
+type IOHandler struct {
+	Read8   func(address uint32) uint8
+	Read16  func(address uint32) uint16
+	Read32  func(address uint32) uint32
+	Write8  func(address uint32, data uint8)
+	Write16 func(address uint32, data uint16)
+	Write32 func(address uint32, data uint32)
+}
+
+/* page table entries for memory in the physical address space */
+func (bus *Bus) MapPages(offset uint32, data []uint8, writable bool) {
+	for i := 0; i < len(data); i += BUS_PAGE_SIZE {
+		page := (*[BUS_PAGE_SIZE]uint8)(data[i : i+BUS_PAGE_SIZE])
+
+		bus.readPages[(offset+uint32(i))>>BUS_PAGE_BITS] = page
+		if writable {
+			bus.writePages[(offset+uint32(i))>>BUS_PAGE_BITS] = page
+		}
+	}
+}
+
+func (bus *Bus) MapMemory() {
+	bus.MapRAM()
+	bus.MapPages(bus.Bios.Offset, bus.Bios.Data[:bus.Bios.Size], false)
+	bus.MapPages(bus.Expansion1.Offset, bus.Expansion1.Data, true)
+}
+
+/* maps the ram mirrors according to the RAM_SIZE register */
+func (bus *Bus) MapRAM() {
+	for page := 0; page < RAM_WINDOW_SIZE>>BUS_PAGE_BITS; page += 1 {
+		bus.readPages[page] = nil
+		bus.writePages[page] = nil
+	}
+
+	window := []uint32{1, 4, 1, 4, 2, 8, 2, 8}[GetRange(bus.ramSize, 9, 3)] * 1024 * 1024
+
+	for offset := uint32(0); offset < window; offset += MAIN_RAM_SIZE {
+		size := MinOf(int(window-offset), MAIN_RAM_SIZE)
+		bus.MapPages(offset, bus.Ram.Data[:size], true)
+	}
+}
+
+/* address pgxp keeps track of a store under (ram mirrors share their entries) */
+func PGXPAddress(address uint32) uint32 {
+	if address < RAM_WINDOW_SIZE {
+		return address & (MAIN_RAM_SIZE - 1)
+	}
+
+	return address
+}
+
+func (bus *Bus) MapIOHandler(offset uint32, size uint32, handler *IOHandler) {
+	for address := offset; address < offset+size; address += 4 {
+		bus.io[(address-IO_OFFSET)/4] = handler
+	}
+}
+
+func MemoryIOHandler(mem *Memory) *IOHandler {
+	return &IOHandler{
+		mem.Read8,
+		mem.Read16,
+		mem.Read32,
+		mem.Write8,
+		mem.Write16,
+		mem.Write32,
+	}
+}
+
+/* has to be called once every device of the console exists */
+func (bus *Bus) MapIO() {
+	core := bus.Core
+
+	bus.MapIOHandler(MC1_OFFSET, MC1_SIZE, &IOHandler{
+		nil,
+		nil,
+		bus.MemoryControl1.Read32,
+		nil,
+		nil,
+		bus.MemoryControl1.Write32,
+	})
+
+	peripheral := MemoryIOHandler(bus.Peripheral)
+	peripheral.Read16 = func(address uint32) uint16 {
+		if address == 0x1f801044 {
+			return 3
+		}
+
+		return bus.Peripheral.Read16(address)
+	}
+	bus.MapIOHandler(bus.Peripheral.Offset, bus.Peripheral.Size, peripheral)
+
+	bus.MapIOHandler(RAM_SIZE_REGISTER, 4, &IOHandler{
+		nil,
+		nil,
+		func(address uint32) uint32 { return bus.ramSize },
+		nil,
+		nil,
+		bus.WriteRAMSize,
+	})
+
+	bus.MapIOHandler(IC_OFFSET, IC_SIZE, &IOHandler{
+		nil,
+		core.Interrupts.Read16,
+		core.Interrupts.Read32,
+		nil,
+		core.Interrupts.Write16,
+		core.Interrupts.Write32,
+	})
+
+	bus.MapIOHandler(DMA_OFFSET, DMA_SIZE, &IOHandler{
+		nil,
+		nil,
+		core.DMA.Read32,
+		nil,
+		nil,
+		core.DMA.Write32,
+	})
+
+	bus.MapIOHandler(TIMERS_OFFSET, TIMERS_SIZE, &IOHandler{
+		nil,
+		core.Timers.Read16,
+		core.Timers.Read32,
+		nil,
+		core.Timers.Write16,
+		core.Timers.Write32,
+	})
+
+	bus.MapIOHandler(CDROM_OFFSET, CDROM_SIZE, &IOHandler{
+		core.CDROM.Read8,
+		nil,
+		nil,
+		core.CDROM.Write8,
+		nil,
+		nil,
+	})
+
+	bus.MapIOHandler(GPU_OFFSET, GPU_SIZE, &IOHandler{
+		nil,
+		nil,
+		core.GPU.Read32,
+		nil,
+		nil,
+		core.GPU.Write32,
+	})
+
+	bus.MapIOHandler(bus.SPU.Offset, bus.SPU.Size, MemoryIOHandler(bus.SPU))
+
+	expansion2 := MemoryIOHandler(bus.Expansion2)
+	expansion2.Write8 = func(address uint32, data uint8) {
+		if address == 0x1F802041 {
+			fmt.Printf("BIOS Trace: %x\n", data)
+		}
+
+		bus.Expansion2.Write8(address, data)
+	}
+	bus.MapIOHandler(bus.Expansion2.Offset, bus.Expansion2.Size, expansion2)
+}
+
+func (bus *Bus) WriteRAMSize(address uint32, data uint32) {
+	bus.ramSize = data
+	bus.MapRAM()
+}
+
+/* handler for an address outside of the page table (nil if there's none) */
+func (bus *Bus) IOHandler(address uint32) *IOHandler {
+	if address-IO_OFFSET < IO_SIZE {
+		return bus.io[(address-IO_OFFSET)/4]
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRAMSizeWindows(t *testing.T) {
+	// memory part of each window (see RAM_SIZE); the rest of the 8MB is HighZ or locked
+	windows := []uint32{1, 4, 1, 4, 2, 8, 2, 8}
+
+	for window, size := range windows {
+		t.Run(fmt.Sprintf("window %d", window), func(t *testing.T) {
+			bus := NewTestGoStation(t).Bus
+			bus.Write32(RAM_SIZE_REGISTER, 0x00000888&^(7<<9)|uint32(window)<<9)
+
+			// a different word at the start of every 512KB of ram
+			for offset := uint32(0); offset < MAIN_RAM_SIZE; offset += 512 * 1024 {
+				bus.Ram.Write32(offset+0x100, 0xa5000000|offset)
+			}
+
+			for offset := uint32(0); offset < RAM_WINDOW_SIZE; offset += 512 * 1024 {
+				data := bus.Read32(offset + 0x100)
+				fault := bus.TakeFault()
+
+				if offset < size*1024*1024 {
+					if expected := 0xa5000000 | offset%MAIN_RAM_SIZE; data != expected || fault {
+						t.Errorf("%08x reads %08x (bus error %t), expected the ram mirror %08x", offset+0x100, data, fault, expected)
+					}
+				} else if !fault {
+					t.Errorf("%08x reads %08x, expected a bus error", offset+0x100, data)
+				}
+			}
+
+			// writes go thru the mirrors and are dropped (with a bus error) in the locked part
+			last := size*1024*1024 - 4
+			bus.Write32(last, 0x12345678)
+			if data := bus.Ram.Read32(last % MAIN_RAM_SIZE); data != 0x12345678 || bus.TakeFault() {
+				t.Errorf("write to the end of the window at %08x didn't reach ram", last)
+			}
+
+			if size < 8 {
+				bus.Write32(last+4, 0x87654321)
+				if !bus.TakeFault() {
+					t.Errorf("write after the end of the window at %08x didn't raise a bus error", last+4)
+				}
+				if data := bus.Ram.Read32((last + 4) % MAIN_RAM_SIZE); data == 0x87654321 {
+					t.Errorf("write after the end of the window at %08x reached ram", last+4)
+				}
+			}
+		})
+	}
+}
+
+func TestRAMSizeDefault(t *testing.T) {
+	bus := NewTestGoStation(t).Bus
+
+	// 8MB window until the bios sets it up; everything mirrors the 2MB
+	if data := bus.Read32(RAM_SIZE_REGISTER); data != 0x00000b88 {
+		t.Errorf("RAM_SIZE is %08x, expected 00000b88", data)
+	}
+
+	bus.Write32(0x00700000, 0xcafef00d)
+	if data := bus.Read32(0x00100000); data != 0xcafef00d {
+		t.Errorf("00100000 reads %08x, expected the word written to its mirror 00700000", data)
+	}
+}
+
+/*
+Baseline for the benchmarks below: how the bus dispatched before the page tables, asking every device in
+turn whether it contains the address (same order as back then, with the devices of today)
+*/
+func ContainsChainRead32(bus *Bus, address uint32) uint32 {
+	core := bus.Core
+
+	switch {
+	case bus.Bios.Contains(address):
+		return bus.Bios.Read32(address)
+	case bus.Ram.Contains(address):
+		return bus.Ram.Read32(address)
+	case bus.ScratchPad.Contains(address):
+		return bus.ScratchPad.Read32(address)
+	case bus.MemoryControl1.Contains(address):
+		return bus.MemoryControl1.Read32(address)
+	case bus.SPU.Contains(address):
+		return bus.SPU.Read32(address)
+	case bus.Peripheral.Contains(address):
+		return bus.Peripheral.Read32(address)
+	case core.Timers.Contains(address):
+		return core.Timers.Read32(address)
+	case core.DMA.Contains(address):
+		return core.DMA.Read32(address)
+	case core.GPU.Contains(address):
+		return core.GPU.Read32(address)
+	case bus.Expansion1.Contains(address):
+		return bus.Expansion1.Read32(address)
+	case bus.Expansion2.Contains(address):
+		return bus.Expansion2.Read32(address)
+	case core.Interrupts.Contains(address):
+		return core.Interrupts.Read32(address)
+	case core.CPU.icache.Contains(address):
+		return core.CPU.icache.Read32(address)
+	}
+
+	return 0
+}
+
+func ContainsChainWrite32(bus *Bus, address uint32, data uint32) {
+	core := bus.Core
+
+	switch {
+	case bus.Ram.Contains(address):
+		core.PGXP.TrackStore(address, data)
+		bus.Ram.Write32(address, data)
+	case bus.ScratchPad.Contains(address):
+		core.PGXP.TrackStore(address, data)
+		bus.ScratchPad.Write32(address, data)
+	case bus.MemoryControl1.Contains(address):
+		bus.MemoryControl1.Write32(address, data)
+	case bus.SPU.Contains(address):
+		bus.SPU.Write32(address, data)
+	case bus.Peripheral.Contains(address):
+		bus.Peripheral.Write32(address, data)
+	case core.Timers.Contains(address):
+		core.Timers.Write32(address, data)
+	case core.DMA.Contains(address):
+		core.DMA.Write32(address, data)
+	case core.GPU.Contains(address):
+		core.GPU.Write32(address, data)
+	case bus.Expansion1.Contains(address):
+		bus.Expansion1.Write32(address, data)
+	case bus.Expansion2.Contains(address):
+		bus.Expansion2.Write32(address, data)
+	case core.Interrupts.Contains(address):
+		core.Interrupts.Write32(address, data)
+	case core.CPU.icache.Contains(address):
+		core.CPU.icache.Write32(address, data)
+	}
+}
+
+var benchmarkAddresses = []struct {
+	name    string
+	address uint32
+}{
+	{"ram", 0x00001000},
+	{"bios", 0x1fc00100}, // read only; a write floats (and was dropped by the contains chain)
+	{"io", 0x1f801074},   // I_MASK
+}
+
+/* go test -run - -bench BusRead32 compares the page tables with the contains chain */
+func BenchmarkBusRead32(b *testing.B) {
+	for _, address := range benchmarkAddresses {
+		b.Run(address.name, func(b *testing.B) {
+			bus := NewTestGoStation(b).Bus
+
+			for i := 0; i < b.N; i += 1 {
+				bus.Read32(address.address)
+			}
+		})
+	}
+}
+
+func BenchmarkContainsChainRead32(b *testing.B) {
+	for _, address := range benchmarkAddresses {
+		b.Run(address.name, func(b *testing.B) {
+			bus := NewTestGoStation(b).Bus
+
+			for i := 0; i < b.N; i += 1 {
+				ContainsChainRead32(bus, address.address)
+			}
+		})
+	}
+}
+
+func BenchmarkBusWrite32(b *testing.B) {
+	for _, address := range benchmarkAddresses {
+		bus := NewTestGoStation(b).Bus
+
+		// unhandled accesses are logged the first time
+		bus.Write32(address.address, 0)
+
+		b.Run(address.name, func(b *testing.B) {
+			for i := 0; i < b.N; i += 1 {
+				bus.Write32(address.address, uint32(i))
+			}
+		})
+	}
+}
+
+func BenchmarkContainsChainWrite32(b *testing.B) {
+	for _, address := range benchmarkAddresses {
+		b.Run(address.name, func(b *testing.B) {
+			bus := NewTestGoStation(b).Bus
+
+			for i := 0; i < b.N; i += 1 {
+				ContainsChainWrite32(bus, address.address, uint32(i))
+			}
+		})
+	}
+}
+
+func TestPeekHasNoSideEffects(t *testing.T) {
+	gostation := NewTestGoStation(t, 0x3c080123)
+	bus := gostation.Bus
+	bus.SetPolicy(BUS_POLICY_STRICT)
+
+	bus.Ram.Write32(0x1000, 0x11223344)
+
+	if data := gostation.CPU.Peek32(0x80001000); data != 0x11223344 {
+		t.Errorf("ram word is %08x, expected 11223344", data)
+	}
+	if data := gostation.CPU.Peek8(0xa0001002); data != 0x22 {
+		t.Errorf("ram byte is %02x, expected 22", data)
+	}
+	if data := gostation.CPU.Peek32(0xbfc00002); data != 0x3c080123 {
+		t.Errorf("unaligned bios word is %08x, expected 3c080123", data)
+	}
+
+	// devices, locked ram, unmapped regions and KSEG2 would panic (strict policy) or change state
+	status := gostation.CDROM.Read8(0x1f801800)
+	for _, address := range []uint32{0x1f801800, 0x1f801803, 0x1f801810, 0x00900000, 0x1fa00000, 0xfffe0000} {
+		if data := gostation.CPU.Peek32(address); data != 0 {
+			t.Errorf("%08x peeks %08x, expected 0", address, data)
+		}
+		if data := gostation.CPU.Peek8(address); data != 0 {
+			t.Errorf("%08x peeks %02x, expected 0", address, data)
+		}
+	}
+
+	if bus.TakeFault() {
+		t.Error("peeking raised a bus error")
+	}
+	if accesses := bus.UnhandledAccesses(); len(accesses) != 0 {
+		t.Errorf("peeking recorded %d unhandled accesses", len(accesses))
+	}
+	if data := gostation.CDROM.Read8(0x1f801800); data != status {
+		t.Errorf("cdrom status changed from %02x to %02x", status, data)
+	}
+}
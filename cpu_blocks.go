@@ -0,0 +1,418 @@
+package main
+
+import (
+	"fmt"
+)
+
+/*
+Cached interpreter
+
+Instead of decoding every instruction thru CPU::ExecutePrimaryOpcode, straight runs of code (up to and
+including the delay slot of the first branch/jump, at most BLOCK_MAX_INSTRUCTIONS and never across a
+code page) are decoded once into a block of opcode handlers keyed by their physical address.
+
+Everything else happens exactly like in the interpreter: the instruction is still fetched (for the
+i-cache and bus timing), interrupts are checked before every instruction and branches/exceptions work
+thru pc/next_pc like before. A block is simply left as soon as pc doesn't point to its next instruction
+(taken branch, exception) or an event of the scheduler becomes due, so delay slots, load delays and
+interrupts behave the same.
+
+Blocks in ram are thrown away when their code page is written to (by the cpu or by dma); all blocks are
+thrown away when the cache stops being isolated after the cpu stored into it (which is what the BIOS
+does to flush the i-cache after loading code). If a fetched opcode doesn't match the block anyway, the
+block is dropped and the instruction is interpreted.
+
+The differential mode (see GoStation::EnableDifferential) runs a second console with the plain
+interpreter in lockstep and stops at the first instruction after which the cpus don't agree.
+*/
+const (
+	BLOCK_MAX_INSTRUCTIONS = 64
+	CODE_PAGE_BITS         = 12
+	CODE_PAGE_SIZE         = 1 << CODE_PAGE_BITS
+)
+
+type OpcodeHandler func(cpu *CPU, opcode uint32)
+
+type BlockInstruction struct {
+	opcode  uint32
+	handler OpcodeHandler
+}
+
+type Block struct {
+	address uint32 /* physical address of the first instruction (canonical for ram mirrors) */
+	code    []BlockInstruction
+}
+
+type BlockCache struct {
+	Core *GoStation
+
+	blocks    map[uint32]*Block
+	pages     map[uint32][]*Block /* blocks by ram code page */
+	codePages [MAIN_RAM_SIZE >> CODE_PAGE_BITS]bool
+}
+
+func NewBlockCache(core *GoStation) *BlockCache {
+	return &BlockCache{
+		core,
+		make(map[uint32]*Block),
+		make(map[uint32][]*Block),
+		[MAIN_RAM_SIZE >> CODE_PAGE_BITS]bool{},
+	}
+}
+
+/*
+Block key of a physical address; only code in ram and BIOS ROM gets cached (ok is false otherwise)
+*/
+func BlockAddress(address uint32) (uint32, bool) {
+	if address < RAM_WINDOW_SIZE {
+		return address & (MAIN_RAM_SIZE - 1), true
+	}
+
+	if address >= 0x1fc00000 && address < 0x1fc80000 {
+		return address, true
+	}
+
+	return 0, false
+}
+
+/* block starting at the (virtual) pc, decoded if needed; nil if the code there doesn't get cached */
+func (cache *BlockCache) Get(pc uint32) *Block {
+	physical := pc & CPUAddressMask(pc>>29)
+
+	address, ok := BlockAddress(physical)
+	if !ok || pc%4 != 0 {
+		return nil
+	}
+
+	if block, ok := cache.blocks[address]; ok {
+		return block
+	}
+
+	block := cache.Compile(physical, address)
+	cache.blocks[address] = block
+
+	if address < MAIN_RAM_SIZE {
+		page := address >> CODE_PAGE_BITS
+		cache.pages[page] = append(cache.pages[page], block)
+		cache.codePages[page] = true
+	}
+
+	return block
+}
+
+func (cache *BlockCache) Compile(physical uint32, address uint32) *Block {
+	bus := cache.Core.Bus
+	block := &Block{address, nil}
+
+	// peeked: decoding mustn't touch devices or log bus errors, the fetch when it runs does that
+	for len(block.code) < BLOCK_MAX_INSTRUCTIONS && bus.Peekable(physical) {
+		opcode := bus.Peek32(physical)
+
+		block.code = append(block.code, BlockInstruction{opcode, DecodeOpcode(opcode)})
+
+		if IsBranch(opcode) {
+			// the delay slot belongs to the block too
+			if (physical+4)%CODE_PAGE_SIZE != 0 && bus.Peekable(physical+4) {
+				opcode := bus.Peek32(physical + 4)
+				block.code = append(block.code, BlockInstruction{opcode, DecodeOpcode(opcode)})
+			}
+			break
+		}
+
+		if EndsBlock(opcode) {
+			break
+		}
+
+		physical += 4
+		if physical%CODE_PAGE_SIZE == 0 {
+			break
+		}
+	}
+
+	return block
+}
+
+/* called for every store to ram */
+func (cache *BlockCache) InvalidateAddress(address uint32) {
+	page := (address & (MAIN_RAM_SIZE - 1)) >> CODE_PAGE_BITS
+	if !cache.codePages[page] {
+		return
+	}
+
+	for _, block := range cache.pages[page] {
+		delete(cache.blocks, block.address)
+	}
+
+	delete(cache.pages, page)
+	cache.codePages[page] = false
+}
+
+func (cache *BlockCache) Invalidate(block *Block) {
+	if cache.blocks[block.address] == block {
+		delete(cache.blocks, block.address)
+	}
+}
+
+func (cache *BlockCache) Flush() {
+	if len(cache.blocks) == 0 {
+		return
+	}
+
+	cache.blocks = make(map[uint32]*Block)
+	cache.pages = make(map[uint32][]*Block)
+	cache.codePages = [MAIN_RAM_SIZE >> CODE_PAGE_BITS]bool{}
+}
+
+/* branches and jumps (which have a delay slot) */
+func IsBranch(opcode uint32) bool {
+	switch GetRange(opcode, 26, 6) {
+	case 0x00:
+		funct := GetRange(opcode, 0, 6)
+		return funct == 0x08 || funct == 0x09 // jr, jalr
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07:
+		return true
+	}
+
+	return false
+}
+
+/* instructions which always (syscall, break) or likely (cop0, illegal) change the flow of the program */
+func EndsBlock(opcode uint32) bool {
+	switch GetRange(opcode, 26, 6) {
+	case 0x00:
+		funct := GetRange(opcode, 0, 6)
+		return funct == 0x0c || funct == 0x0d
+	case 0b010000:
+		return true
+	}
+
+	return false
+}
+
+/* same decoding as CPU::ExecutePrimaryOpcode and CPU::ExecuteSecondaryOpcode */
+func DecodeOpcode(opcode uint32) OpcodeHandler {
+	switch GetRange(opcode, 26, 6) {
+	case 0x00:
+		return DecodeSecondaryOpcode(opcode)
+	case 0x01:
+		return (*CPU).OpBcondZ
+	case 0x02:
+		return (*CPU).OpJump
+	case 0x03:
+		return (*CPU).OpJAL
+	case 0x04:
+		return (*CPU).OpBEQ
+	case 0x05:
+		return (*CPU).OpBNE
+	case 0x06:
+		return (*CPU).OpBLEZ
+	case 0x07:
+		return (*CPU).OpBGTZ
+	case 0x08:
+		return (*CPU).OpADDI
+	case 0x09:
+		return (*CPU).OpADDIU
+	case 0x0a:
+		return (*CPU).OpSLTI
+	case 0x0b:
+		return (*CPU).OpSLTIU
+	case 0x0c:
+		return (*CPU).OpANDI
+	case 0x0d:
+		return (*CPU).OpORI
+	case 0x0e:
+		return (*CPU).OpXORI
+	case 0x0f:
+		return (*CPU).OpLUI
+	case 0x20:
+		return (*CPU).OpLoadByte
+	case 0x21:
+		return (*CPU).OpLoadHWord
+	case 0x22:
+		return (*CPU).OpLoadWordLeft
+	case 0x23:
+		return (*CPU).OpLoadWord
+	case 0x24:
+		return (*CPU).OpLoadByteU
+	case 0x25:
+		return (*CPU).OpLoadHWordU
+	case 0x26:
+		return (*CPU).OpLoadWordRight
+	case 0x28:
+		return (*CPU).OpStoreByte
+	case 0x29:
+		return (*CPU).OpStoreHWord
+	case 0x2a:
+		return (*CPU).OpStoreWordLeft
+	case 0x2b:
+		return (*CPU).OpStoreWord
+	case 0x2e:
+		return (*CPU).OpStoreWordRight
+	case 0x30:
+		return (*CPU).OpLWC0
+	case 0x31:
+		return (*CPU).OpLWC1
+	case 0x32:
+		return (*CPU).OpLWC2
+	case 0x33:
+		return (*CPU).OpLWC3
+	case 0x38:
+		return (*CPU).OpSWC0
+	case 0x39:
+		return (*CPU).OpSWC1
+	case 0x3a:
+		return (*CPU).OpSWC2
+	case 0x3b:
+		return (*CPU).OpSWC3
+	case 0b010000:
+		return (*CPU).ExecuteCOP0Opcode
+	case 0b010001:
+		return (*CPU).ExecuteCOP1Opcode
+	case 0b010010:
+		return (*CPU).ExecuteCOP2Opcode
+	case 0b010011:
+		return (*CPU).ExecuteCOP3Opcode
+	default:
+		return (*CPU).OpIllegal
+	}
+}
+
+func DecodeSecondaryOpcode(opcode uint32) OpcodeHandler {
+	switch GetRange(opcode, 0, 6) {
+	case 0x00:
+		return (*CPU).OpSLL
+	case 0x02:
+		return (*CPU).OpSRL
+	case 0x03:
+		return (*CPU).OpSRA
+	case 0x04:
+		return (*CPU).OpSLLV
+	case 0x06:
+		return (*CPU).OpSRLV
+	case 0x07:
+		return (*CPU).OpSRAV
+	case 0x08:
+		return (*CPU).OpJR
+	case 0x09:
+		return (*CPU).OpJALR
+	case 0x0c:
+		return (*CPU).OpSYS
+	case 0x0d:
+		return (*CPU).OpBRK
+	case 0x10:
+		return (*CPU).OpMFHI
+	case 0x11:
+		return (*CPU).OpMTHI
+	case 0x12:
+		return (*CPU).OpMFLO
+	case 0x13:
+		return (*CPU).OpMTLO
+	case 0x18:
+		return (*CPU).OpMULT
+	case 0x19:
+		return (*CPU).OpMULTU
+	case 0x1a:
+		return (*CPU).OpDIV
+	case 0x1b:
+		return (*CPU).OpDIVU
+	case 0x20:
+		return (*CPU).OpADD
+	case 0x21:
+		return (*CPU).OpADDU
+	case 0x22:
+		return (*CPU).OpSUB
+	case 0x23:
+		return (*CPU).OpSUBU
+	case 0x24:
+		return (*CPU).OpAND
+	case 0x25:
+		return (*CPU).OpOR
+	case 0x26:
+		return (*CPU).OpXOR
+	case 0x27:
+		return (*CPU).OpNOR
+	case 0x2a:
+		return (*CPU).OpSLT
+	case 0x2b:
+		return (*CPU).OpSLTU
+	default:
+		return (*CPU).OpIllegal
+	}
+}
+
+func (gostation *GoStation) EnableBlockCache() {
+	gostation.CPU.blocks = NewBlockCache(gostation)
+	gostation.Bus.blocks = gostation.CPU.blocks
+}
+
+/* runs the block at pc until it is left or an event becomes due */
+func (gostation *GoStation) RunBlock() {
+	cpu := gostation.CPU
+
+	block := cpu.blocks.Get(cpu.pc)
+	if block == nil {
+		gostation.StepCPU()
+		return
+	}
+
+	start := cpu.pc
+	for i, instruction := range block.code {
+		if i > 0 && (cpu.pc != start+uint32(i*4) || gostation.Scheduler.Due()) {
+			return
+		}
+
+		if !gostation.ExecuteInstruction(instruction.opcode, instruction.handler) {
+			// the code changed behind our back
+			cpu.blocks.Invalidate(block)
+			return
+		}
+	}
+}
+
+/*
+Starts a second console which only uses the plain interpreter; it has to be called before anything
+ran. After every instruction both cpus are compared. The tty output of the second console is dropped
+since it's the same.
+*/
+func (gostation *GoStation) EnableDifferential(pathToBios string) {
+	gostation.reference = NewGoStation(pathToBios)
+	gostation.reference.Bus.SetPolicy(gostation.Bus.policy)
+	gostation.reference.SetTTY(nil)
+}
+
+func (gostation *GoStation) CompareWithReference() {
+	reference := gostation.reference
+	pc := reference.CPU.pc
+
+	reference.StepCPU()
+	if reference.Scheduler.Due() {
+		reference.Scheduler.RunEvents()
+	}
+
+	a, b := gostation.CPU, reference.CPU
+	same := a.r == b.r && a.pc == b.pc && a.next_pc == b.next_pc && a.hi == b.hi && a.lo == b.lo &&
+		a.cop0.sr == b.cop0.sr && a.cop0.cause == b.cop0.cause && a.cop0.epc == b.cop0.epc &&
+		gostation.Scheduler.Now() == reference.Scheduler.Now()
+
+	if same {
+		return
+	}
+
+	fmt.Printf("[GoStation::CompareWithReference] cpus disagree after the instruction at %08x\n", pc)
+	fmt.Printf("%-10s %-10s %-10s\n", "", "cached", "interpreter")
+	for i := 0; i < 32; i += 1 {
+		if a.r[i] != b.r[i] {
+			fmt.Printf("%-10s %08x   %08x\n", fmt.Sprintf("r%d", i), a.r[i], b.r[i])
+		}
+	}
+	fmt.Printf("%-10s %08x   %08x\n", "pc", a.pc, b.pc)
+	fmt.Printf("%-10s %08x   %08x\n", "next_pc", a.next_pc, b.next_pc)
+	fmt.Printf("%-10s %08x   %08x\n", "hi", a.hi, b.hi)
+	fmt.Printf("%-10s %08x   %08x\n", "lo", a.lo, b.lo)
+	fmt.Printf("%-10s %08x   %08x\n", "sr", a.cop0.sr, b.cop0.sr)
+	fmt.Printf("%-10s %08x   %08x\n", "cause", a.cop0.cause, b.cop0.cause)
+	fmt.Printf("%-10s %08x   %08x\n", "epc", a.cop0.epc, b.cop0.epc)
+	fmt.Printf("%-10s %-10d %-10d\n", "cycles", gostation.Scheduler.Now(), reference.Scheduler.Now())
+
+	panic("[GoStation::CompareWithReference] differential test failed")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+/* calls the routine at the address in rs count times (r10 is the counter) */
+func AsmCallLoop(rs int, count int) []uint32 {
+	return Program(
+		AsmORI(10, 0, count),
+		AsmJALR(31, rs),
+		AsmNOP(),
+		AsmADDIU(10, 10, -1),
+		AsmBNE(10, 0, -4),
+		AsmNOP(),
+	)
+}
+
+/* stores addiu r2, r2, imm over the first instruction of the routine (r8 points to it) */
+func AsmPatchRoutine(imm int) []uint32 {
+	return Program(AsmLI(9, AsmADDIU(2, 2, imm)), AsmSW(9, 0, 8))
+}
+
+/*
+Bios which copies a routine adding to r2 into ram, calls it and changes it in between: thru KSEG1 (blocks
+have to be thrown away when the code gets written), thru KSEG0 (the i-cache keeps the old code until it
+gets flushed thru the isolated cache). Returns the program and the address where it ends.
+*/
+func SelfModifyingProgram() ([]uint32, uint32) {
+	program := Program(
+		// enable the i-cache (r12 points to the cache control register)
+		AsmLUI(12, 0xfffe),
+		AsmLI(13, 0x0001e988),
+		AsmSW(13, 0x130, 12),
+
+		AsmLUI(8, 0xa001),  // r8: routine at 00010000h thru KSEG1
+		AsmLUI(11, 0x8001), // r11: same thru KSEG0
+		AsmPatchRoutine(1),
+		AsmLI(9, AsmJR(31)),
+		AsmSW(9, 4, 8),
+		AsmSW(0, 8, 8),
+
+		AsmCallLoop(8, 3),
+		AsmPatchRoutine(0x100),
+		AsmCallLoop(8, 2),
+
+		// fill the i-cache and change the code behind its back; it still runs the old code
+		AsmCallLoop(11, 2),
+		AsmPatchRoutine(0x1000),
+		AsmCallLoop(11, 1),
+
+		// flush the i-cache line like the bios does: tag test mode, isolate the cache and store to the line
+		AsmORI(13, 0, 0x804),
+		AsmSW(13, 0x130, 12),
+		AsmLUI(13, 0x0001),
+		AsmMTC0(13, 12),
+		AsmNOP(),
+		AsmSW(0, 0, 0),
+		AsmMTC0(0, 12),
+		AsmNOP(),
+		AsmLI(13, 0x0001e988),
+		AsmSW(13, 0x130, 12),
+
+		AsmCallLoop(11, 1),
+	)
+
+	end := 0xbfc00000 + uint32(len(program))*4
+	program = append(program, AsmBEQ(0, 0, -1), AsmNOP())
+
+	return program, end
+}
+
+func TestCachedInterpreterMatchesInterpreter(t *testing.T) {
+	program, end := SelfModifyingProgram()
+	bios := WriteTestBIOS(t, program...)
+
+	interpreter := NewGoStation(bios)
+	RunUntil(t, interpreter, end)
+
+	// 3*1 + 2*100h, 2*100h + 1*100h (stale i-cache), 1000h after the flush
+	if r2 := interpreter.CPU.r[2]; r2 != 0x1503 {
+		t.Fatalf("interpreter: r2 is %x, expected 1503", r2)
+	}
+
+	cached := NewGoStation(bios)
+	cached.EnableBlockCache()
+	// checked against the interpreter after every instruction
+	cached.EnableDifferential(bios)
+	RunUntil(t, cached, end)
+
+	a, b := cached.CPU, interpreter.CPU
+	if a.r != b.r || a.hi != b.hi || a.lo != b.lo || a.cop0.sr != b.cop0.sr {
+		t.Errorf("registers differ\ncached      %08x\ninterpreter %08x", a.r, b.r)
+	}
+	if cached.Scheduler.Now() != interpreter.Scheduler.Now() {
+		t.Errorf("cached interpreter took %d cycles, interpreter %d", cached.Scheduler.Now(), interpreter.Scheduler.Now())
+	}
+}
+
+func TestBlocksAreInvalidated(t *testing.T) {
+	gostation := NewTestGoStation(t)
+	gostation.EnableBlockCache()
+
+	cpu := gostation.CPU
+	cache := cpu.blocks
+
+	routine := Program(AsmADDIU(2, 2, 1), AsmJR(31), AsmNOP())
+	for i, instruction := range routine {
+		cpu.Write32(0xa0010000+uint32(i)*4, instruction)
+	}
+
+	compile := func() *Block {
+		block := cache.Get(0x80010000)
+		if block == nil || len(block.code) != len(routine) {
+			t.Fatalf("routine compiled into %v", block)
+		}
+		return block
+	}
+
+	// stores to a ram mirror of the code page
+	block := compile()
+	cpu.Write32(0xa0611000, 0)
+	if cache.blocks[0x10000] != block {
+		t.Error("store to another code page threw the block away")
+	}
+	cpu.Write8(0xa0610009, 0)
+	if _, ok := cache.blocks[0x10000]; ok {
+		t.Error("store into the block's code page kept it")
+	}
+
+	// by dma
+	compile()
+	gostation.Bus.WriteRAM32(0x10008, 0)
+	if _, ok := cache.blocks[0x10000]; ok {
+		t.Error("dma into the block's code page kept it")
+	}
+
+	// stores into the isolated cache flush everything once the cache isn't isolated anymore
+	compile()
+	cache.Get(0xbfc00000)
+	cpu.cop0.ModifyRegister(12, cpu.cop0.sr|1<<16)
+	cpu.Write32(0x00000ff0, 0)
+	cpu.Write32(0x00000000, 0)
+	if len(cache.blocks) != 2 {
+		t.Errorf("%d blocks left while the cache is isolated, expected the 2 blocks", len(cache.blocks))
+	}
+	cpu.cop0.ModifyRegister(12, cpu.cop0.sr&^(1<<16))
+	if len(cache.blocks) != 0 {
+		t.Errorf("%d blocks left after isolated cache stores", len(cache.blocks))
+	}
+
+	// isolating the cache without storing into it keeps them
+	compile()
+	cpu.cop0.ModifyRegister(12, cpu.cop0.sr|1<<16)
+	cpu.cop0.ModifyRegister(12, cpu.cop0.sr&^(1<<16))
+	if len(cache.blocks) != 1 {
+		t.Errorf("%d blocks left after isolating the cache, expected the block", len(cache.blocks))
+	}
+}
+
+func TestCompilingHasNoSideEffects(t *testing.T) {
+	gostation := NewTestGoStation(t)
+	gostation.EnableBlockCache()
+	bus := gostation.Bus
+
+	// 1MB of ram and 7MB locked (window 0): only the fetch of the cpu raises the bus error
+	bus.Write32(RAM_SIZE_REGISTER, 0x00000088)
+
+	if block := gostation.CPU.blocks.Get(0x80100000); block == nil || len(block.code) != 0 {
+		t.Errorf("locked ram compiled into %v, expected an empty block", block)
+	}
+	if accesses := bus.UnhandledAccesses(); len(accesses) != 0 || bus.TakeFault() {
+		t.Errorf("compiling recorded %d unhandled accesses or a bus error", len(accesses))
+	}
+}
+
+func TestDifferentialPrintsTTYOnce(t *testing.T) {
+	program := Program(
+		// kernel A function table stub at a0h: jr r31
+		AsmLI(9, AsmJR(31)),
+		AsmSW(9, 0xa0, 0),
+		AsmSW(0, 0xa4, 0),
+
+		// A(3Ch) putchar('x')
+		AsmORI(4, 0, 'x'),
+		AsmORI(9, 0, 0x3c),
+		AsmORI(8, 0, 0xa0),
+		AsmJALR(31, 8),
+		AsmNOP(),
+	)
+	end := 0xbfc00000 + uint32(len(program))*4
+	program = append(program, AsmBEQ(0, 0, -1), AsmNOP())
+
+	bios := WriteTestBIOS(t, program...)
+
+	var tty strings.Builder
+	gostation := NewGoStation(bios)
+	gostation.SetTTY(&tty)
+	gostation.EnableBlockCache()
+	gostation.EnableDifferential(bios)
+
+	RunUntil(t, gostation, end)
+
+	if tty.String() != "x" {
+		t.Errorf("tty output is %q, expected \"x\"", tty.String())
+	}
+	if gostation.reference.tty != nil {
+		t.Error("the reference console prints to the tty")
+	}
+}
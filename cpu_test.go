@@ -0,0 +1,476 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	testBIOS       = flag.String("bios", "", "bios for the tests which run executables on a whole console")
+	cpuTestExes    = flag.String("cpu-tests", "", "comma separated test executables (ps1-tests' cpu/*/*.exe, amidog's psx_cpu.exe) to run with -bios")
+	cpuTestFrames  = flag.Int("cpu-test-frames", 600, "frames each of -cpu-tests runs for")
+	ansiEscape     = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	exceptionStart = uint32(0x80000080) /* vector with BEV=0 (sr is 0 after reset) */
+)
+
+/*
+Runs test executables and compares what they print to the tty with what they print on a real console:
+ps1-tests keeps that log as psx.log next to each test's executable (one line per test, eg. the cycles
+each access takes or "pass"/"fail - ..." per assertion), for other tests (like amidog's psx_cpu) put the
+output of a real console next to the executable as <name>.log. Every line has to match; colours (ansi
+escapes), carriage returns and blank lines are ignored.
+
+	git clone --depth 1 https://github.com/JaCzekanski/ps1-tests
+	go test -run CPUTestExecutables -bios SCPH1001.BIN -cpu-tests $(find ps1-tests/cpu -name '*.exe' | paste -sd,)
+
+The bios can't be bundled so CI only runs them when it is given one (see .github/workflows/tests.yaml).
+*/
+func TestCPUTestExecutables(t *testing.T) {
+	if *testBIOS == "" || *cpuTestExes == "" {
+		t.Skip("needs -bios and -cpu-tests")
+	}
+
+	for _, exe := range strings.Split(*cpuTestExes, ",") {
+		exe := exe
+
+		t.Run(filepath.Base(exe), func(t *testing.T) {
+			expected, err := ReadReferenceLog(exe)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tty strings.Builder
+
+			gostation := NewGoStation(*testBIOS)
+			gostation.SetTTY(&tty)
+			gostation.LoadExecutable(exe)
+
+			for frame := 0; frame < *cpuTestFrames; frame += 1 {
+				gostation.Update()
+			}
+
+			for _, mismatch := range CompareTTY(tty.String(), expected) {
+				t.Error(mismatch)
+			}
+		})
+	}
+}
+
+/* psx.log next to the executable (ps1-tests) or <executable without extension>.log */
+func ReadReferenceLog(exe string) (string, error) {
+	paths := []string{
+		strings.TrimSuffix(exe, filepath.Ext(exe)) + ".log",
+		filepath.Join(filepath.Dir(exe), "psx.log"),
+	}
+
+	for _, path := range paths {
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), nil
+		}
+	}
+
+	return "", os.ErrNotExist
+}
+
+/* lines of tty output without colours, carriage returns, trailing spaces and blank lines */
+func TTYLines(output string) []string {
+	lines := []string{}
+
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+/* returns a message for every line of output which isn't the one of the real console */
+func CompareTTY(output, expected string) []string {
+	got := TTYLines(output)
+	want := TTYLines(expected)
+	mismatches := []string{}
+
+	for i, line := range want {
+		switch {
+		case i >= len(got):
+			mismatches = append(mismatches, "missing: "+line)
+		case got[i] != line:
+			mismatches = append(mismatches, "got "+got[i]+"\n    expected "+line)
+		}
+	}
+	for _, line := range got[MinOf(len(want), len(got)):] {
+		mismatches = append(mismatches, "unexpected: "+line)
+	}
+
+	return mismatches
+}
+
+func TestCompareTTY(t *testing.T) {
+	expected := "\x1b[32mpass\x1b[0m - add\r\n\nsub:  3 cycles\r\n"
+
+	tests := []struct {
+		name       string
+		output     string
+		mismatches int
+	}{
+		{"same", "pass - add\nsub:  3 cycles\n", 0},
+		{"colours and blank lines", "\x1b[32mpass\x1b[0m - add \n\n\nsub:  3 cycles", 0},
+		{"failed assertion", "\x1b[31mfail\x1b[0m - add:12 given 0x1, expected 0x2\nsub:  3 cycles\n", 1},
+		{"different number", "pass - add\nsub:  4 cycles\n", 1},
+		{"didn't finish", "pass - add\n", 1},
+		{"nothing printed", "", 2},
+		{"more lines", "pass - add\nsub:  3 cycles\nok\n", 1},
+	}
+
+	for _, test := range tests {
+		if mismatches := CompareTTY(test.output, expected); len(mismatches) != test.mismatches {
+			t.Errorf("%s: %d mismatches %q, expected %d", test.name, len(mismatches), mismatches, test.mismatches)
+		}
+	}
+}
+
+/*
+Runs a program from the bios until it gets to its end or to an exception; r8 points to 8 words of
+uncached ram (at a0000100h) the program can use
+*/
+func RunCPUProgram(t *testing.T, parts ...interface{}) *CPU {
+	t.Helper()
+
+	program := Program(AsmLI(8, 0xa0000100), Program(parts...))
+	end := 0xbfc00000 + uint32(len(program))*4
+	program = append(program, AsmBEQ(0, 0, -1), AsmNOP())
+
+	gostation := NewTestGoStation(t, program...)
+	cpu := gostation.CPU
+
+	for i := 0; cpu.pc != end && cpu.pc != exceptionStart; i += 1 {
+		if i == 10000 {
+			t.Fatalf("pc is %08x after %d steps", cpu.pc, i)
+		}
+
+		gostation.Step()
+	}
+
+	return cpu
+}
+
+func ExceptionCode(cpu *CPU) uint32 {
+	return GetRange(cpu.cop0.cause, 2, 5)
+}
+
+/* stores value into the word at offset of the test ram (r9 is clobbered) */
+func AsmStoreTestWord(offset int, value uint32) []uint32 {
+	return Program(AsmLI(9, value), AsmSW(9, offset, 8))
+}
+
+func TestLoadDelay(t *testing.T) {
+	cpu := RunCPUProgram(t,
+		AsmStoreTestWord(0, 0x11111111),
+		AsmStoreTestWord(4, 0x22222222),
+		AsmORI(2, 0, 0x55),
+
+		AsmLW(2, 0, 8),
+		AsmADDU(3, 2, 0), // the load isn't there yet
+		AsmADDU(4, 2, 0),
+
+		// two loads in a row into the same register: the first one never lands
+		AsmORI(2, 0, 0x55),
+		AsmLW(2, 0, 8),
+		AsmLW(2, 4, 8),
+		AsmADDU(5, 2, 0),
+		AsmADDU(6, 2, 0),
+
+		// an alu result written in the delay slot wins over the load
+		AsmLW(7, 0, 8),
+		AsmORI(7, 0, 0x77),
+		AsmNOP(),
+	)
+
+	expected := map[int]uint32{3: 0x55, 4: 0x11111111, 5: 0x55, 6: 0x22222222, 7: 0x77}
+	for r, value := range expected {
+		if cpu.r[r] != value {
+			t.Errorf("r%d is %08x, expected %08x", r, cpu.r[r], value)
+		}
+	}
+}
+
+func TestUnalignedLoadsMergeWithLoadsInTheirDelaySlot(t *testing.T) {
+	cpu := RunCPUProgram(t,
+		AsmStoreTestWord(0, 0x11223344),
+		AsmStoreTestWord(4, 0x55667788),
+
+		// lwl merges with the value lw is loading, not with the old register
+		AsmORI(2, 0, 0),
+		AsmLW(2, 0, 8),
+		AsmLWL(2, 5, 8),
+		AsmNOP(),
+
+		// lwr merges with the value of the lwl before it
+		AsmLI(5, 0xaaaaaaaa),
+		AsmLWL(5, 5, 8),
+		AsmLWR(5, 2, 8),
+		AsmNOP(),
+
+		// the usual unaligned load: lwr+lwl of bytes 1..4
+		AsmLWR(6, 1, 8),
+		AsmLWL(6, 4, 8),
+		AsmNOP(),
+	)
+
+	expected := map[int]uint32{2: 0x77883344, 5: 0x77881122, 6: 0x88112233}
+	for r, value := range expected {
+		if cpu.r[r] != value {
+			t.Errorf("r%d is %08x, expected %08x", r, cpu.r[r], value)
+		}
+	}
+}
+
+func TestExceptionsInDelaySlots(t *testing.T) {
+	overflow := Program(AsmLI(9, 0x7fffffff), AsmADDI(10, 9, 1))
+
+	tests := []struct {
+		name   string
+		branch uint32
+		bd, bt bool
+	}{
+		{"taken branch", AsmBEQ(0, 0, 4), true, true},
+		{"branch not taken", AsmBNE(0, 0, 4), true, false},
+		{"no branch", AsmNOP(), false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// r8 setup is 2 instructions, li is 2 more: the branch is at bfc00010h
+			cpu := RunCPUProgram(t, overflow[0], overflow[1], test.branch, overflow[2])
+
+			if cpu.pc != exceptionStart {
+				t.Fatalf("pc is %08x, expected the exception vector", cpu.pc)
+			}
+			if code := ExceptionCode(cpu); code != EXC_OVERFLOW {
+				t.Errorf("exception %x, expected overflow", code)
+			}
+
+			epc := uint32(0xbfc00014)
+			if test.bd {
+				epc = 0xbfc00010
+			}
+			if cpu.cop0.epc != epc {
+				t.Errorf("epc is %08x, expected %08x", cpu.cop0.epc, epc)
+			}
+
+			if bd := TestBit(cpu.cop0.cause, 31); bd != test.bd {
+				t.Errorf("BD is %t, expected %t", bd, test.bd)
+			}
+			if bt := TestBit(cpu.cop0.cause, 30); bt != test.bt {
+				t.Errorf("BT is %t, expected %t", bt, test.bt)
+			}
+			if cpu.r[10] != 0 {
+				t.Errorf("r10 is %08x, the faulting instruction must not write it", cpu.r[10])
+			}
+		})
+	}
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     uint32
+		op       uint32
+		overflow bool
+		expected uint32
+	}{
+		{"add", 0x7ffffffe, 1, AsmADD(10, 11, 12), false, 0x7fffffff},
+		{"add positive overflow", 0x7fffffff, 1, AsmADD(10, 11, 12), true, 0},
+		{"add negative overflow", 0x80000000, 0xffffffff, AsmADD(10, 11, 12), true, 0},
+		{"add mixed signs", 0x80000000, 0x7fffffff, AsmADD(10, 11, 12), false, 0xffffffff},
+		{"addi positive overflow", 0x7fff8001, 0, AsmADDI(10, 11, 0x7fff), true, 0},
+		{"addi negative overflow", 0x80000000, 0, AsmADDI(10, 11, -1), true, 0},
+		{"addi", 0x80000000, 0, AsmADDI(10, 11, 1), false, 0x80000001},
+		{"addu wraps", 0x7fffffff, 1, AsmADDU(10, 11, 12), false, 0x80000000},
+		{"addiu wraps", 0x80000000, 0, AsmADDIU(10, 11, -1), false, 0x7fffffff},
+		{"sub", 0x80000001, 1, AsmSUB(10, 11, 12), false, 0x80000000},
+		{"sub negative overflow", 0x80000000, 1, AsmSUB(10, 11, 12), true, 0},
+		{"sub positive overflow", 0x7fffffff, 0xffffffff, AsmSUB(10, 11, 12), true, 0},
+		{"sub of min", 0, 0x80000000, AsmSUB(10, 11, 12), true, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the operation is at bfc00018h, after the setup of r8, r11 and r12
+			cpu := RunCPUProgram(t, AsmLI(11, test.a), AsmLI(12, test.b), test.op)
+
+			if overflow := cpu.pc == exceptionStart; overflow != test.overflow {
+				t.Fatalf("overflow is %t, expected %t", overflow, test.overflow)
+			}
+
+			if test.overflow {
+				if code := ExceptionCode(cpu); code != EXC_OVERFLOW {
+					t.Errorf("exception %x, expected overflow", code)
+				}
+				if cpu.cop0.epc != 0xbfc00018 {
+					t.Errorf("epc is %08x, expected bfc00018", cpu.cop0.epc)
+				}
+			}
+
+			if cpu.r[10] != test.expected {
+				t.Errorf("r10 is %08x, expected %08x", cpu.r[10], test.expected)
+			}
+		})
+	}
+}
+
+func TestUnalignedStoresInUserMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       uint32
+		address  uint32
+		expected uint32 /* word at 00001000h afterwards; 0 if the store raises AdES */
+	}{
+		{"swl kernel address", AsmSWL(9, 1, 8), 0x80001000, 0},
+		{"swr kernel address", AsmSWR(9, 1, 8), 0xa0001000, 0},
+		{"swl", AsmSWL(9, 1, 8), 0x00001000, 0x55661122},
+		{"swr", AsmSWR(9, 1, 8), 0x00001000, 0x22334488},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gostation := NewTestGoStation(t)
+			cpu := gostation.CPU
+
+			gostation.Bus.Ram.Write32(0x1000, 0x55667788)
+			cpu.r[8] = test.address
+			cpu.r[9] = 0x11223344
+			cpu.cop0.sr |= 1 << 1 // KUc: user mode
+
+			cpu.ExecutePrimaryOpcode(test.op)
+
+			if test.expected == 0 {
+				if code := ExceptionCode(cpu); code != EXC_ADDR_ERROR_STORE {
+					t.Errorf("exception %x, expected AdES", code)
+				}
+				if cpu.cop0.r8 != test.address+1 {
+					t.Errorf("BadVaddr is %08x, expected %08x", cpu.cop0.r8, test.address+1)
+				}
+				if data := gostation.Bus.Ram.Read32(0x1000); data != 0x55667788 {
+					t.Errorf("ram was written: %08x", data)
+				}
+				return
+			}
+
+			if cpu.cop0.cause != 0 {
+				t.Errorf("cause is %08x, expected no exception", cpu.cop0.cause)
+			}
+			if data := gostation.Bus.Ram.Read32(0x1000); data != test.expected {
+				t.Errorf("ram is %08x, expected %08x", data, test.expected)
+			}
+		})
+	}
+}
+
+/* stores control into the cache control register (r9 and r10 are clobbered) */
+func AsmCacheControl(control uint32) []uint32 {
+	return Program(AsmLI(9, 0xfffe0130), AsmLI(10, control), AsmSW(10, 0, 9))
+}
+
+func TestScratchPadAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		control  uint32
+		address  uint32
+		busError bool
+	}{
+		{"kuseg", 0x88, 0x1f800010, false},
+		{"kseg0", 0x88, 0x9f800010, false},
+		{"kseg1", 0x88, 0xbf800010, true},
+		{"disabled", 0x00, 0x1f800010, true},
+		{"only one enable bit", 0x80, 0x9f800010, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the store is at bfc0002ch, after the setup of r8, the cache control and r11/r12
+			cpu := RunCPUProgram(t,
+				AsmCacheControl(test.control),
+				AsmLI(11, test.address),
+				AsmLI(12, 0x12345678),
+				AsmSW(12, 0, 11),
+				AsmLW(13, 0, 11),
+				AsmNOP(),
+			)
+			scratchpad := cpu.Core.Bus.ScratchPad.Read32(0x1f800010)
+
+			if test.busError {
+				if cpu.pc != exceptionStart || ExceptionCode(cpu) != EXC_BUS_ERROR_DATA {
+					t.Fatalf("pc is %08x with exception %x, expected a bus error", cpu.pc, ExceptionCode(cpu))
+				}
+				if cpu.cop0.epc != 0xbfc0002c {
+					t.Errorf("epc is %08x, expected the store at bfc0002c", cpu.cop0.epc)
+				}
+				if scratchpad != 0 {
+					t.Errorf("the scratchpad was written: %08x", scratchpad)
+				}
+				return
+			}
+
+			if cpu.pc == exceptionStart {
+				t.Fatalf("exception %x, expected none", ExceptionCode(cpu))
+			}
+			if scratchpad != 0x12345678 || cpu.r[13] != 0x12345678 {
+				t.Errorf("scratchpad is %08x and loads %08x, expected 12345678", scratchpad, cpu.r[13])
+			}
+		})
+	}
+}
+
+func TestNoInstructionFetchFromTheScratchPad(t *testing.T) {
+	for _, address := range []uint32{0x1f800000, 0x9f800000} {
+		cpu := RunCPUProgram(t, AsmCacheControl(0x88), AsmLI(11, address), AsmJR(11), AsmNOP())
+
+		if cpu.pc != exceptionStart || ExceptionCode(cpu) != EXC_BUS_ERROR_FETCH {
+			t.Fatalf("jump to %08x: pc is %08x with exception %x, expected a bus error on fetch", address, cpu.pc, ExceptionCode(cpu))
+		}
+		if cpu.cop0.epc != address {
+			t.Errorf("epc is %08x, expected %08x", cpu.cop0.epc, address)
+		}
+	}
+}
+
+/* dma addresses are masked to ram, so a scratchpad address goes to ram instead */
+func TestDMADoesNotReachTheScratchPad(t *testing.T) {
+	cpu := RunCPUProgram(t,
+		AsmCacheControl(0x88),
+		AsmLI(11, 0x1f800010),
+		AsmLI(12, 0xcafef00d),
+		AsmSW(12, 0, 11),
+
+		// clear an ordering table of 4 entries ending at 1f800010h
+		AsmLI(9, 0xbf8010e0),
+		AsmSW(11, 0, 9),
+		AsmORI(10, 0, 4),
+		AsmSW(10, 4, 9),
+		AsmLI(10, 0x11000002),
+		AsmSW(10, 8, 9),
+	)
+	bus := cpu.Core.Bus
+
+	if cpu.pc == exceptionStart {
+		t.Fatalf("exception %x, expected none", ExceptionCode(cpu))
+	}
+	if data := bus.ScratchPad.Read32(0x1f800010); data != 0xcafef00d {
+		t.Errorf("the dma wrote %08x into the scratchpad", data)
+	}
+
+	expected := []uint32{0x00000c, 0x000008, 0x000004, 0xffffff}
+	for i, entry := range expected {
+		address := uint32(0x10 - i*4)
+		if data := bus.Ram.Read32(address); data != entry {
+			t.Errorf("%08x is %08x, expected %08x", address, data, entry)
+		}
+	}
+}
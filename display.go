@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	DISPLAY_MAX_WIDTH  = 640
+	DISPLAY_MAX_HEIGHT = 480
+)
+
+const (
+	DEINTERLACE_WEAVE = iota /* show both fields at once (ie. whatever is in vram) */
+	DEINTERLACE_BOB          /* show only the most recently completed field and double its lines */
+)
+
+/*
+Display output stage; converts the display area in vram (GP1(05h)..GP1(08h)) into an RGB image
+*/
+type DisplayOutput struct {
+	DeinterlaceMode int
+	Image           *image.RGBA
+}
+
+func NewDisplayOutput(deinterlaceMode int) *DisplayOutput {
+	return &DisplayOutput{
+		deinterlaceMode,
+		image.NewRGBA(image.Rect(0, 0, 320, 240)),
+	}
+}
+
+func (display *DisplayOutput) ToggleDeinterlaceMode() {
+	if display.DeinterlaceMode == DEINTERLACE_WEAVE {
+		display.DeinterlaceMode = DEINTERLACE_BOB
+	} else {
+		display.DeinterlaceMode = DEINTERLACE_WEAVE
+	}
+}
+
+/*
+Renders the display area; the image is scaled up by the internal resolution of the renderer. 24bit
+display areas (usually fmvs uploaded by the cpu) are always read from the native vram.
+*/
+func (display *DisplayOutput) Render(gpu *GPU) *image.RGBA {
+	output := gpu.renderer.Output(gpu)
+	scale := output.scale
+
+	width := int(gpu.horizResolution) * scale
+	height := int(gpu.vertResolution) * scale
+
+	if display.Image.Bounds().Dx() != width || display.Image.Bounds().Dy() != height {
+		display.Image = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	for y := 0; y < height; y += 1 {
+		srcY := y / scale
+		if gpu.Interlaced480() && display.DeinterlaceMode == DEINTERLACE_BOB {
+			srcY = (srcY &^ 1) | gpu.completedField
+		}
+
+		vramY := Modulo(gpu.displayVramStartY+srcY, VRAM_HEIGHT)
+
+		for x := 0; x < width; x += 1 {
+			if gpu.displayDisable {
+				display.Image.SetRGBA(x, y, color.RGBA{0, 0, 0, 0xff})
+				continue
+			}
+
+			if gpu.displayColourDepth {
+				display.Image.SetRGBA(x, y, gpu.vram.Read24(gpu.displayVramStartX*2+(x/scale)*3, vramY))
+			} else {
+				outX := Modulo(gpu.displayVramStartX*scale+x, output.width)
+				outY := vramY*scale + y%scale
+
+				display.Image.SetRGBA(x, y, output.Read15(outX, outY))
+			}
+		}
+	}
+
+	return display.Image
+}
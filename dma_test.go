@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+)
+
+/*
+Dma goes to ram directly: it isn't limited by the memory window of RAM_SIZE and the cpu store which
+starts it doesn't see a bus error
+*/
+func TestDMAIgnoresTheRAMWindow(t *testing.T) {
+	gostation := NewTestGoStation(t)
+	cpu := gostation.CPU
+
+	// 1MB window; 00180000h is locked for the cpu
+	gostation.Bus.Write32(RAM_SIZE_REGISTER, 0x00000088)
+
+	// clear an ordering table of 4 entries ending at 00180010h
+	cpu.Write32(0xbf8010e0, 0x00180010)
+	cpu.Write32(0xbf8010e4, 4)
+	cpu.Write32(0xbf8010e8, 0x11000002)
+
+	if cpu.faulted || GetRange(cpu.cop0.cause, 2, 5) == EXC_BUS_ERROR_DATA {
+		t.Fatal("starting the dma raised a bus error")
+	}
+
+	expected := []uint32{0x18000c, 0x180008, 0x180004, 0xffffff}
+	for i, entry := range expected {
+		address := uint32(0x180010 - i*4)
+		if data := gostation.Bus.Ram.Read32(address); data != entry {
+			t.Errorf("%08x is %08x, expected %08x", address, data, entry)
+		}
+	}
+}
@@ -1,58 +1,111 @@
-package main
-
-const FIFO_MAX_SIZE = 16
-
-type FIFO[T any] struct {
-	buffer  [FIFO_MAX_SIZE]T
-	maxSize int /* should be less than 16 */
-	head    int
-	tail    int
-}
-
-func NewFIFO[T any]() *FIFO[T] {
-	return &FIFO[T]{
-		[FIFO_MAX_SIZE]T{},
-		FIFO_MAX_SIZE,
-		0,
-		0,
-	}
-}
-
-func (fifo *FIFO[T]) Reset(maxSize int) {
-	fifo.buffer = [16]T{}
-	fifo.maxSize = maxSize
-	fifo.head = 0
-	fifo.tail = 0
-}
-
-func (fifo *FIFO[T]) Push(data T) {
-	if fifo.tail == FIFO_MAX_SIZE {
-		return
-	}
-
-	fifo.buffer[fifo.tail] = data
-	fifo.tail += 1
-}
-
-func (fifo *FIFO[T]) Pop() T {
-	data := fifo.buffer[fifo.head]
-	fifo.head += 1
-	return data
-}
-
-func (fifo *FIFO[T]) Front() T {
-	return fifo.buffer[fifo.head]
-}
-
-func (fifo *FIFO[T]) Done() bool {
-	return fifo.tail == fifo.maxSize
-}
-
-/* call this before using FIFO::Pop */
-func (fifo *FIFO[T]) Empty() bool {
-	return fifo.head == fifo.tail
-}
-
-func (fifo *FIFO[T]) Size() int {
-	return fifo.tail - fifo.head
-}
+package main
+
+const FIFO_MAX_SIZE = 16
+
+type FIFO[T any] struct {
+	buffer  [FIFO_MAX_SIZE]T
+	maxSize int /* should be less than 16 */
+	head    int
+	tail    int
+}
+
+func NewFIFO[T any]() *FIFO[T] {
+	return &FIFO[T]{
+		[FIFO_MAX_SIZE]T{},
+		FIFO_MAX_SIZE,
+		0,
+		0,
+	}
+}
+
+func (fifo *FIFO[T]) Reset(maxSize int) {
+	fifo.buffer = [16]T{}
+	fifo.maxSize = maxSize
+	fifo.head = 0
+	fifo.tail = 0
+}
+
+func (fifo *FIFO[T]) Push(data T) {
+	if fifo.tail == FIFO_MAX_SIZE {
+		return
+	}
+
+	fifo.buffer[fifo.tail] = data
+	fifo.tail += 1
+}
+
+func (fifo *FIFO[T]) Pop() T {
+	data := fifo.buffer[fifo.head]
+	fifo.head += 1
+	return data
+}
+
+func (fifo *FIFO[T]) Front() T {
+	return fifo.buffer[fifo.head]
+}
+
+func (fifo *FIFO[T]) Done() bool {
+	return fifo.tail == fifo.maxSize
+}
+
+/* call this before using FIFO::Pop */
+func (fifo *FIFO[T]) Empty() bool {
+	return fifo.head == fifo.tail
+}
+
+func (fifo *FIFO[T]) Size() int {
+	return fifo.tail - fifo.head
+}
+
+/*
+Unlike FIFO (which is used to collect a known number of arguments), Queue is a circular buffer
+that keeps accepting data as long as there's room for it
+*/
+type Queue[T any] struct {
+	buffer [FIFO_MAX_SIZE]T
+	head   int
+	size   int
+}
+
+func NewQueue[T any]() *Queue[T] {
+	return &Queue[T]{
+		[FIFO_MAX_SIZE]T{},
+		0,
+		0,
+	}
+}
+
+func (queue *Queue[T]) Clear() {
+	queue.head = 0
+	queue.size = 0
+}
+
+/* call Queue::Full before this otherwise data might get lost */
+func (queue *Queue[T]) Push(data T) {
+	if queue.size == FIFO_MAX_SIZE {
+		return
+	}
+
+	queue.buffer[(queue.head+queue.size)%FIFO_MAX_SIZE] = data
+	queue.size += 1
+}
+
+/* call Queue::Empty before this */
+func (queue *Queue[T]) Pop() T {
+	data := queue.buffer[queue.head]
+	queue.head = (queue.head + 1) % FIFO_MAX_SIZE
+	queue.size -= 1
+	return data
+}
+
+func (queue *Queue[T]) Empty() bool {
+	return queue.size == 0
+}
+
+func (queue *Queue[T]) Full() bool {
+	return queue.size == FIFO_MAX_SIZE
+}
+
+func (queue *Queue[T]) Size() int {
+	return queue.size
+}
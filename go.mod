@@ -2,4 +2,7 @@ module gostation
 
 go 1.19
 
-require github.com/veandco/go-sdl2 v0.4.25
+require (
+	github.com/veandco/go-sdl2 v0.4.25
+	golang.org/x/image v0.18.0
+)
@@ -1,116 +1,211 @@
-package main
-
-import (
-	"fmt"
-)
-
-/*
-Timing:
-
-	CPU Clock   =  33.868800MHz (44100Hz*300h)
-	Video Clock =  53.222400MHz (44100Hz*300h*11/7)
-*/
-const (
-	CPU_CYCLES_PER_SEC = 33868800
-)
-
-type GoStation struct {
-	Bus        *Bus
-	CPU        *CPU
-	GPU        *GPU
-	DMA        *DMA
-	CDROM      *CDROM
-	Interrupts *Interrupts
-
-	cycles         uint32
-	cyclesPerFrame uint32
-	log            bool
-}
-
-func NewGoStation(pathToBios string) *GoStation {
-	gostation := GoStation{}
-
-	gostation.Bus = NewBus(&gostation, pathToBios)
-	gostation.CPU = NewCPU(&gostation)
-	gostation.GPU = NewGPU(&gostation)
-	gostation.DMA = NewDMA(&gostation)
-	gostation.CDROM = NewCDROM(&gostation)
-	gostation.Interrupts = NewInterrupts(&gostation)
-
-	gostation.cycles = 0
-	gostation.cyclesPerFrame = CPU_CYCLES_PER_SEC / 60 // NTSC mode for default
-
-	return &gostation
-}
-
-func (gostation *GoStation) LoadExecutable(pathToExe string) {
-	exe := NewPSXExe(pathToExe)
-
-	// emulate till pc=80030000h
-	for gostation.CPU.pc != 0x80030000 {
-		gostation.Step()
-	}
-
-	// copy contents of executable into the main ram
-	start := exe.Header.TAddr
-	size := exe.Header.TSize
-	for i := uint32(0); i < size; i += 1 {
-		gostation.CPU.Write8(start+i, exe.Data[i])
-	}
-
-	gostation.CPU.pc = exe.Header.PC0
-	gostation.CPU.next_pc = exe.Header.PC0 + 4
-
-	gostation.CPU.modifyReg(28, exe.Header.GP0)
-
-	if exe.Header.SAddr != 0 {
-		gostation.CPU.modifyReg(29, exe.Header.SAddr+exe.Header.SSize)
-		gostation.CPU.modifyReg(30, exe.Header.SAddr+exe.Header.SSize)
-	}
-
-	fmt.Printf("[GoStation::LoadExecutable] executable successfully loaded; pc is now in %08x\n", gostation.CPU.pc)
-}
-
-func (gostation *GoStation) Update() {
-	for gostation.Step() {
-	}
-}
-
-func (gostation *GoStation) Step() bool {
-	if gostation.log {
-		gostation.CPU.Log(true)
-	}
-
-	gostation.CheckBIOSFunctionCalls(false)
-	gostation.CPU.Step()
-	gostation.GPU.Step(2)
-	gostation.CDROM.Step()
-
-	gostation.cycles += 2 // each instruction takes about 2 cycles
-
-	if gostation.cycles == gostation.cyclesPerFrame {
-		gostation.cycles = 0
-		return false
-	}
-
-	return true
-}
-
-/*
-https://psx-spx.consoledev.net/kernelbios/#bios-function-summary
-*/
-func (gostation *GoStation) CheckBIOSFunctionCalls(log bool) {
-	switch gostation.CPU.pc {
-	case 0xa0: /* A function */
-		fn := gostation.CPU.reg(9)
-		BIOSAFunction(gostation, fn, log)
-	case 0xb0: /* B function */
-		fn := gostation.CPU.reg(9)
-		BIOSBFunction(gostation, fn, log)
-	case 0xc0: /* C function */
-		fn := gostation.CPU.reg(9)
-		if log {
-			fmt.Printf("[GoStation::CheckBIOSFunctionCalls] BIOS C(%02Xh)\n", fn)
-		}
-	}
-}
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+Timing:
+
+	CPU Clock   =  33.868800MHz (44100Hz*300h)
+	Video Clock =  53.222400MHz (44100Hz*300h*11/7)
+*/
+const (
+	CPU_CYCLES_PER_SEC = 33868800
+)
+
+type GoStation struct {
+	Bus        *Bus
+	CPU        *CPU
+	GPU        *GPU
+	DMA        *DMA
+	CDROM      *CDROM
+	Interrupts *Interrupts
+	Timers     *Timers
+	SPU        *SPU
+	PGXP       *PGXP
+	Scheduler  *Scheduler
+
+	frameEvent     *Event
+	frameDone      bool
+	cyclesPerFrame uint32
+	stallCycles    uint32 /* cycles the cpu has to wait for devices (eg. dma waiting for the gpu fifo) */
+	log            bool
+
+	video        *AVIRecorder   /* nil unless a video is being recorded */
+	videoDisplay *DisplayOutput /* renders the frames of the video */
+
+	reference *GoStation /* interpreter only console the cpu is checked against (nil unless differential) */
+	biosTrace *BIOSTrace /* nil unless kernel calls are traced */
+	tty       io.Writer  /* where the kernel's putchar/puts output goes (nil drops it) */
+}
+
+func NewGoStation(pathToBios string) *GoStation {
+	gostation := GoStation{}
+
+	gostation.Scheduler = NewScheduler()
+	gostation.PGXP = NewPGXP()
+	gostation.tty = os.Stdout
+
+	gostation.Bus = NewBus(&gostation, pathToBios)
+	gostation.CPU = NewCPU(&gostation)
+	gostation.GPU = NewGPU(&gostation)
+	gostation.DMA = NewDMA(&gostation)
+	gostation.CDROM = NewCDROM(&gostation)
+	gostation.Interrupts = NewInterrupts(&gostation)
+	gostation.Timers = NewTimers(&gostation)
+	gostation.SPU = NewSPU(&gostation)
+
+	gostation.Bus.MapIO()
+
+	gostation.cyclesPerFrame = CPU_CYCLES_PER_SEC / 60 // NTSC mode for default
+	gostation.frameEvent = NewEvent("frame", gostation.EndFrame)
+	gostation.Scheduler.Schedule(gostation.frameEvent, uint64(gostation.cyclesPerFrame))
+	gostation.GPU.ScheduleEvent()
+
+	return &gostation
+}
+
+func (gostation *GoStation) LoadExecutable(pathToExe string) {
+	exe := NewPSXExe(pathToExe)
+
+	// emulate till pc=80030000h
+	for gostation.CPU.pc != 0x80030000 {
+		gostation.Step()
+	}
+
+	// copy contents of executable into the main ram
+	start := exe.Header.TAddr
+	size := exe.Header.TSize
+	for i := uint32(0); i < size; i += 1 {
+		gostation.CPU.Write8(start+i, exe.Data[i])
+	}
+
+	// the BIOS would flush the i-cache after loading an executable
+	gostation.CPU.icache.Flush()
+
+	gostation.CPU.pc = exe.Header.PC0
+	gostation.CPU.next_pc = exe.Header.PC0 + 4
+
+	gostation.CPU.modifyReg(28, exe.Header.GP0)
+
+	if exe.Header.SAddr != 0 {
+		gostation.CPU.modifyReg(29, exe.Header.SAddr+exe.Header.SSize)
+		gostation.CPU.modifyReg(30, exe.Header.SAddr+exe.Header.SSize)
+	}
+
+	fmt.Printf("[GoStation::LoadExecutable] executable successfully loaded; pc is now in %08x\n", gostation.CPU.pc)
+
+	if gostation.reference != nil {
+		gostation.reference.LoadExecutable(pathToExe)
+	}
+}
+
+func (gostation *GoStation) SetTTY(tty io.Writer) {
+	gostation.tty = tty
+}
+
+func (gostation *GoStation) Update() {
+	gostation.frameDone = false
+
+	for !gostation.frameDone {
+		gostation.RunCPU()
+		gostation.Scheduler.RunEvents()
+	}
+
+	// the frontend is going to read vram
+	gostation.GPU.Sync()
+	gostation.GPU.SyncRenderThreads()
+	gostation.GPU.renderer.SyncVRAM(gostation.GPU.vram)
+
+	gostation.GPU.TraceFrame()
+	gostation.GPU.primitiveLog.EndFrame()
+	gostation.RecordVideoFrame(gostation.SPU.TakeSamples())
+}
+
+/* runs instructions until the next event is due */
+func (gostation *GoStation) RunCPU() {
+	if gostation.CPU.blocks != nil {
+		for !gostation.Scheduler.Due() {
+			gostation.RunBlock()
+		}
+		return
+	}
+
+	for !gostation.Scheduler.Due() {
+		gostation.StepCPU()
+	}
+}
+
+/* executes a single instruction (and the events which became due); returns false at the end of a frame */
+func (gostation *GoStation) Step() bool {
+	gostation.frameDone = false
+
+	gostation.StepCPU()
+	if gostation.Scheduler.Due() {
+		gostation.Scheduler.RunEvents()
+	}
+
+	return !gostation.frameDone
+}
+
+func (gostation *GoStation) StepCPU() {
+	gostation.ExecuteInstruction(0, nil)
+}
+
+/* executes the instruction at pc, see CPU::StepDecoded */
+func (gostation *GoStation) ExecuteInstruction(opcode uint32, handler OpcodeHandler) bool {
+	if gostation.log {
+		gostation.CPU.Log(true)
+	}
+
+	gostation.CheckBIOSFunctionCalls(false)
+	matches := gostation.CPU.StepDecoded(opcode, handler)
+
+	// one cycle for the instruction itself plus whatever its memory accesses took
+	cycles := 1 + gostation.Bus.TakeCycles() + gostation.stallCycles
+	gostation.stallCycles = 0
+
+	gostation.Scheduler.Advance(cycles)
+
+	if gostation.reference != nil {
+		gostation.CompareWithReference()
+	}
+
+	return matches
+}
+
+func (gostation *GoStation) EndFrame() {
+	gostation.frameDone = true
+	gostation.Scheduler.ScheduleAt(gostation.frameEvent, gostation.frameEvent.Time()+uint64(gostation.cyclesPerFrame))
+}
+
+/* makes the cpu wait for the given amount of cycles after the current instruction */
+func (gostation *GoStation) Stall(cycles uint32) {
+	gostation.stallCycles += cycles
+}
+
+/*
+https://psx-spx.consoledev.net/kernelbios/#bios-function-summary
+*/
+func (gostation *GoStation) CheckBIOSFunctionCalls(log bool) {
+	switch gostation.CPU.pc {
+	case 0xa0: /* A function */
+		fn := gostation.CPU.reg(9)
+		BIOSAFunction(gostation, fn, log)
+	case 0xb0: /* B function */
+		fn := gostation.CPU.reg(9)
+		BIOSBFunction(gostation, fn, log)
+	case 0xc0: /* C function */
+		fn := gostation.CPU.reg(9)
+		if log {
+			fmt.Printf("[GoStation::CheckBIOSFunctionCalls] BIOS C(%02Xh) %s\n", fn, BIOSFunctionName("C", fn))
+		}
+	}
+
+	if gostation.biosTrace != nil {
+		gostation.biosTrace.Step()
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/* bios made of program (at bfc00000h, followed by zeroes which are nops); returns its path */
+func WriteTestBIOS(tb testing.TB, program ...uint32) string {
+	tb.Helper()
+
+	bios := make([]uint8, 1024*512)
+	for i, instruction := range program {
+		binary.LittleEndian.PutUint32(bios[i*4:], instruction)
+	}
+
+	path := filepath.Join(tb.TempDir(), "bios.bin")
+	if err := os.WriteFile(path, bios, 0644); err != nil {
+		tb.Fatal(err)
+	}
+
+	return path
+}
+
+/* console booting a bios made of program */
+func NewTestGoStation(tb testing.TB, program ...uint32) *GoStation {
+	tb.Helper()
+
+	return NewGoStation(WriteTestBIOS(tb, program...))
+}
+
+/* runs the interpreter (or the cached interpreter if the block cache is enabled) until pc is at end */
+func RunUntil(tb testing.TB, gostation *GoStation, end uint32) {
+	tb.Helper()
+
+	for i := 0; gostation.CPU.pc != end; i += 1 {
+		if i == 100000 {
+			tb.Fatalf("pc is %08x after %d steps, expected it to get to %08x", gostation.CPU.pc, i, end)
+		}
+
+		if gostation.CPU.blocks != nil {
+			gostation.RunBlock()
+		} else {
+			gostation.StepCPU()
+		}
+
+		if gostation.Scheduler.Due() {
+			gostation.Scheduler.RunEvents()
+		}
+	}
+}
+
+/*
+Assembler for test programs; registers are numbers, offsets of branches are in instructions relative to
+the delay slot
+*/
+func AsmI(op, rs, rt int, imm int) uint32 {
+	return uint32(op)<<26 | uint32(rs)<<21 | uint32(rt)<<16 | uint32(uint16(imm))
+}
+
+func AsmR(funct, rs, rt, rd, shamt int) uint32 {
+	return uint32(rs)<<21 | uint32(rt)<<16 | uint32(rd)<<11 | uint32(shamt)<<6 | uint32(funct)
+}
+
+func AsmNOP() uint32                     { return 0 }
+func AsmLUI(rt, imm int) uint32          { return AsmI(0x0f, 0, rt, imm) }
+func AsmORI(rt, rs, imm int) uint32      { return AsmI(0x0d, rs, rt, imm) }
+func AsmADDI(rt, rs, imm int) uint32     { return AsmI(0x08, rs, rt, imm) }
+func AsmADDIU(rt, rs, imm int) uint32    { return AsmI(0x09, rs, rt, imm) }
+func AsmADDU(rd, rs, rt int) uint32      { return AsmR(0x21, rs, rt, rd, 0) }
+func AsmADD(rd, rs, rt int) uint32       { return AsmR(0x20, rs, rt, rd, 0) }
+func AsmSUB(rd, rs, rt int) uint32       { return AsmR(0x22, rs, rt, rd, 0) }
+func AsmLW(rt, offset, base int) uint32  { return AsmI(0x23, base, rt, offset) }
+func AsmLWL(rt, offset, base int) uint32 { return AsmI(0x22, base, rt, offset) }
+func AsmLWR(rt, offset, base int) uint32 { return AsmI(0x26, base, rt, offset) }
+func AsmSW(rt, offset, base int) uint32  { return AsmI(0x2b, base, rt, offset) }
+func AsmSWL(rt, offset, base int) uint32 { return AsmI(0x2a, base, rt, offset) }
+func AsmSWR(rt, offset, base int) uint32 { return AsmI(0x2e, base, rt, offset) }
+func AsmBEQ(rs, rt, offset int) uint32   { return AsmI(0x04, rs, rt, offset) }
+func AsmBNE(rs, rt, offset int) uint32   { return AsmI(0x05, rs, rt, offset) }
+func AsmJR(rs int) uint32                { return AsmR(0x08, rs, 0, 0, 0) }
+func AsmJALR(rd, rs int) uint32          { return AsmR(0x09, rs, 0, rd, 0) }
+func AsmMTC0(rt, rd int) uint32          { return 0x10<<26 | 0x04<<21 | uint32(rt)<<16 | uint32(rd)<<11 }
+func AsmMFC0(rt, rd int) uint32          { return 0x10<<26 | 0x00<<21 | uint32(rt)<<16 | uint32(rd)<<11 }
+func AsmRFE() uint32                     { return 0x10<<26 | 0x10<<21 | 0x10 }
+func AsmSYSCALL() uint32                 { return AsmR(0x0c, 0, 0, 0, 0) }
+
+/* loads a 32 bit constant into rt */
+func AsmLI(rt int, value uint32) []uint32 {
+	return []uint32{AsmLUI(rt, int(value>>16)), AsmORI(rt, rt, int(value&0xffff))}
+}
+
+func Program(parts ...interface{}) []uint32 {
+	program := []uint32{}
+	for _, part := range parts {
+		switch part := part.(type) {
+		case uint32:
+			program = append(program, part)
+		case []uint32:
+			program = append(program, part...)
+		default:
+			panic("[Program] instructions are uint32 or []uint32")
+		}
+	}
+
+	return program
+}
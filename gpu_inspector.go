@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+/*
+Primitive inspector
+
+While enabled, every polygon and rectangle the gpu draws is logged together with its command words and
+the drawing state it was drawn with. The log of the last completed frame can be searched for the
+primitives covering a pixel (see PrimitiveLog::At) which tells which command produced it.
+
+Coverage is computed from the geometry (same fill rules as the rasterizer) so it includes pixels which
+were skipped because of transparent texels or the mask bit. The frontend shows the primitives it found
+as text on top of the vram view (see InspectorText).
+*/
+const (
+	PRIMITIVE_LOG_MAX_ENTRIES = 1 << 16 /* per frame; anything beyond is dropped */
+
+	INSPECTOR_TEXT_WIDTH = VRAM_WIDTH / 2 /* the text covers the half of the vram view without the pixel */
+)
+
+type PrimitiveRecord struct {
+	Index int      /* position in the frame */
+	Shape int      /* PRIMITIVE_POLYGON or PRIMITIVE_RECTANGLE */
+	Attr  uint32   /* PATTR_* or RATTR_* bits */
+	Words []uint32 /* command and arguments as sent to GP0 */
+
+	/*
+		polygons: 3 or 4 vertexes (drawing offset applied)
+		rectangles: top left (with the texture coordinates) and bottom right (exclusive) corners
+	*/
+	Vertices []Vertex
+
+	Textured     bool
+	ClutX        int
+	ClutY        int
+	TexPageUBase int
+	TexPageVBase int
+	TexFormat    int
+	STMode       int
+
+	/* inclusive */
+	DrawingAreaX1 int
+	DrawingAreaY1 int
+	DrawingAreaX2 int
+	DrawingAreaY2 int
+}
+
+type PrimitiveLog struct {
+	enabled bool
+	current []*PrimitiveRecord /* frame being drawn */
+	frame   []*PrimitiveRecord /* last completed frame */
+}
+
+func NewPrimitiveLog() *PrimitiveLog {
+	return &PrimitiveLog{
+		false,
+		nil,
+		nil,
+	}
+}
+
+func (log *PrimitiveLog) SetEnabled(enabled bool) {
+	log.enabled = enabled
+	log.current = nil
+
+	if !enabled {
+		log.frame = nil
+	}
+}
+
+func (log *PrimitiveLog) Enabled() bool {
+	return log.enabled
+}
+
+/* called by the emulator at the end of each frame */
+func (log *PrimitiveLog) EndFrame() {
+	if !log.enabled {
+		return
+	}
+
+	log.frame = log.current
+	log.current = nil
+}
+
+/* primitives drawn during the last completed frame, in drawing order */
+func (log *PrimitiveLog) Frame() []*PrimitiveRecord {
+	return log.frame
+}
+
+/* primitives of the last completed frame which cover the vram pixel (x, y), in drawing order */
+func (log *PrimitiveLog) At(x, y int) []*PrimitiveRecord {
+	var records []*PrimitiveRecord
+
+	for _, record := range log.frame {
+		if record.Covers(x, y) {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+/* called before the primitive in the fifo gets drawn */
+func (gpu *GPU) LogPrimitive() {
+	log := gpu.primitiveLog
+	if !log.enabled || len(log.current) >= PRIMITIVE_LOG_MAX_ENTRIES {
+		return
+	}
+
+	words := make([]uint32, gpu.fifo.tail)
+	copy(words, gpu.fifo.buffer[:gpu.fifo.tail])
+
+	record := gpu.DecodePrimitive(words)
+	record.Index = len(log.current)
+
+	log.current = append(log.current, record)
+}
+
+/*
+Decodes the command words of a polygon or rectangle (see GPU::ProcessPolygonCommand and
+GPU::ProcessRectangleCommand for the argument formats) with the current drawing state
+*/
+func (gpu *GPU) DecodePrimitive(words []uint32) *PrimitiveRecord {
+	record := &PrimitiveRecord{
+		0,
+		gpu.shape,
+		gpu.shape_attr,
+		words,
+		nil,
+		false,
+		0,
+		0,
+		gpu.txBase * 64,
+		gpu.tyBase * 256,
+		gpu.textureFormat,
+		gpu.semiTransparency,
+		gpu.drawingAreaX1,
+		gpu.drawingAreaY1,
+		gpu.drawingAreaX2,
+		gpu.drawingAreaY2,
+	}
+
+	if gpu.shape == PRIMITIVE_POLYGON {
+		record.Textured = TestBit(gpu.shape_attr, PATTR_TEXTURE)
+		isShaded := TestBit(gpu.shape_attr, PATTR_GOURAUD)
+
+		count := 3
+		if TestBit(gpu.shape_attr, PATTR_QUAD) {
+			count = 4
+		}
+
+		colour := words[0]
+		i := 1
+
+		for n := 0; n < count; n += 1 {
+			if isShaded && n > 0 {
+				colour = words[i]
+				i += 1
+			}
+
+			xy := words[i]
+			i += 1
+
+			var uv uint32
+			if record.Textured {
+				uv = words[i]
+				i += 1
+
+				switch n {
+				case 0:
+					record.ClutX = int(GetRange(uv, 16, 6) * 16)
+					record.ClutY = int(GetRange(uv, 22, 9))
+				case 1:
+					texPage := uv >> 16
+					record.TexPageUBase = int(GetRange(texPage, 0, 4) * 64)
+					record.TexPageVBase = int(GetRange(texPage, 4, 1) * 256)
+					record.STMode = int(GetRange(texPage, 5, 2))
+					record.TexFormat = int(GetRange(texPage, 7, 2))
+				}
+			}
+
+			record.Vertices = append(record.Vertices, *NewVertex(xy, colour, uv, gpu.drawingXOffset, gpu.drawingYOffset))
+		}
+
+		return record
+	}
+
+	record.Textured = TestBit(gpu.shape_attr, RATTR_TEXTURE)
+
+	var uv uint32
+	i := 2
+	if record.Textured {
+		uv = words[i]
+		i += 1
+
+		record.ClutX = int(GetRange(uv, 16, 6) * 16)
+		record.ClutY = int(GetRange(uv, 22, 9))
+	}
+
+	topLeft := NewVertex(words[1], words[0], uv, gpu.drawingXOffset, gpu.drawingYOffset)
+
+	var width, height int
+	switch GetRange(gpu.shape_attr, 3, 2) {
+	case RSIZE_1x1:
+		width, height = 1, 1
+	case RSIZE_8x8:
+		width, height = 8, 8
+	case RSIZE_16x16:
+		width, height = 16, 16
+	default:
+		width = MinOf(int(words[i]&0xffff), VRAM_WIDTH-1)
+		height = MinOf(int(words[i]>>16), VRAM_HEIGHT-1)
+	}
+
+	bottomRight := *topLeft
+	bottomRight.x += width
+	bottomRight.y += height
+
+	record.Vertices = []Vertex{*topLeft, bottomRight}
+
+	return record
+}
+
+/* whether the primitive covers the vram pixel (x, y) */
+func (record *PrimitiveRecord) Covers(x, y int) bool {
+	if x < record.DrawingAreaX1 || x > record.DrawingAreaX2 || y < record.DrawingAreaY1 || y > record.DrawingAreaY2 {
+		return false
+	}
+
+	v := record.Vertices
+
+	if record.Shape == PRIMITIVE_RECTANGLE {
+		return x >= v[0].x && x < v[1].x && y >= v[0].y && y < v[1].y
+	}
+
+	if TriangleCovers(&v[0], &v[1], &v[2], x, y) {
+		return true
+	}
+
+	return len(v) == 4 && TriangleCovers(&v[1], &v[2], &v[3], x, y)
+}
+
+/* see GPU::ClipTriangle and TriangleEdges */
+func TriangleCovers(v1, v2, v3 *Vertex, x, y int) bool {
+	if MaxOf(v1.x, v2.x, v3.x)-MinOf(v1.x, v2.x, v3.x) >= VRAM_WIDTH ||
+		MaxOf(v1.y, v2.y, v3.y)-MinOf(v1.y, v2.y, v3.y) >= VRAM_HEIGHT {
+		return false
+	}
+
+	area := Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y)
+	if area == 0 {
+		return false
+	}
+
+	if area < 0 {
+		v2, v3 = v3, v2
+	}
+
+	first, last := NewTriangleEdges(v1, v2, v3).Span(y, x, x)
+
+	return first <= last
+}
+
+/* human readable description of the primitive and its command words */
+func (record *PrimitiveRecord) String() string {
+	var sb strings.Builder
+
+	name := "polygon"
+	if record.Shape == PRIMITIVE_RECTANGLE {
+		name = "rectangle"
+	}
+
+	fmt.Fprintf(&sb, "#%d %s (GP0(%02Xh))", record.Index, name, record.Words[0]>>24)
+
+	// same bits for polygons and rectangles
+	if TestBit(record.Attr, PATTR_SEMI_TRANSPARENT) {
+		fmt.Fprintf(&sb, " semi-transparent (mode %d)", record.STMode)
+	}
+	if record.Textured {
+		fmt.Fprintf(&sb, " textured (%s, page %d,%d, clut %d,%d)",
+			[]string{"4bit", "8bit", "15bit", "reserved"}[record.TexFormat],
+			record.TexPageUBase,
+			record.TexPageVBase,
+			record.ClutX,
+			record.ClutY,
+		)
+		if TestBit(record.Attr, PATTR_RAW_TEXTURE) {
+			sb.WriteString(" raw")
+		}
+	}
+	if record.Shape == PRIMITIVE_POLYGON && TestBit(record.Attr, PATTR_GOURAUD) {
+		sb.WriteString(" gouraud")
+	}
+
+	fmt.Fprintf(&sb, "\n  drawing area %d,%d - %d,%d\n", record.DrawingAreaX1, record.DrawingAreaY1, record.DrawingAreaX2, record.DrawingAreaY2)
+
+	for i, v := range record.Vertices {
+		if record.Shape == PRIMITIVE_RECTANGLE && i == 1 {
+			fmt.Fprintf(&sb, "  bottom right %d,%d (exclusive)\n", v.x, v.y)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "  vertex %d,%d colour %d,%d,%d", v.x, v.y, v.r, v.g, v.b)
+		if record.Textured {
+			fmt.Fprintf(&sb, " uv %d,%d", v.u, v.v)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("  words")
+	for _, word := range record.Words {
+		fmt.Fprintf(&sb, " %08x", word)
+	}
+
+	return sb.String()
+}
+
+/* lines describing the primitives which cover (x, y), wrapped at the given number of columns */
+func InspectorLines(records []*PrimitiveRecord, x, y int, columns int) []string {
+	lines := []string{fmt.Sprintf("%d primitives cover %d,%d", len(records), x, y)}
+
+	for _, record := range records {
+		for _, line := range strings.Split(record.String(), "\n") {
+			for len(line) > columns {
+				cut := strings.LastIndex(line[:columns], " ")
+				if cut <= 4 {
+					cut = columns
+				}
+				lines = append(lines, line[:cut])
+				line = "    " + strings.TrimLeft(line[cut:], " ")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+/*
+Text of the inspected pixel for the vram view: white on translucent black, INSPECTOR_TEXT_WIDTH wide and
+as high as the lines need (at most the height of vram; what doesn't fit is cut off)
+*/
+func InspectorText(records []*PrimitiveRecord, x, y int) *image.RGBA {
+	face := basicfont.Face7x13
+	lines := InspectorLines(records, x, y, (INSPECTOR_TEXT_WIDTH-4)/face.Advance)
+
+	maxLines := (VRAM_HEIGHT - 4) / face.Height
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines-1], "...")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, INSPECTOR_TEXT_WIDTH, len(lines)*face.Height+4))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 0xc0}), image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: img, Src: image.White, Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(2, 2+i*face.Height+face.Ascent)
+		drawer.DrawString(line)
+	}
+
+	return img
+}
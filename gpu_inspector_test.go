@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspectorText(t *testing.T) {
+	gpu := NewTestGPU()
+	gpu.primitiveLog.SetEnabled(true)
+
+	// a gouraud quad has 8 words, which don't fit into 40 columns
+	SendGP0(gpu, 0x38ff0000, VertexWord(0, 0), 0x00ff00, VertexWord(64, 0), 0x0000ff, VertexWord(0, 64), 0xffffff, VertexWord(64, 64))
+	gpu.primitiveLog.EndFrame()
+
+	records := gpu.primitiveLog.At(10, 10)
+	lines := InspectorLines(records, 10, 10, 40)
+
+	if len(records) != 1 || lines[0] != "1 primitives cover 10,10" {
+		t.Fatalf("lines are %q", lines)
+	}
+	words := ""
+	for _, line := range lines {
+		if len(line) > 40 {
+			t.Errorf("%q is longer than 40 columns", line)
+		}
+		if strings.HasPrefix(line, "  words") || strings.HasPrefix(line, "    ") {
+			words += " " + strings.TrimSpace(strings.TrimPrefix(line, "  words"))
+		}
+	}
+	if expected := " 38ff0000 00000000 0000ff00 00000040 000000ff 00400000 00ffffff 00400040"; words != expected {
+		t.Errorf("words are%s, expected%s", words, expected)
+	}
+
+	// more primitives than fit on the vram view are cut off
+	many := make([]*PrimitiveRecord, 100)
+	for i := range many {
+		many[i] = records[0]
+	}
+	if img := InspectorText(many, 10, 10); img.Bounds().Dx() != INSPECTOR_TEXT_WIDTH || img.Bounds().Dy() > VRAM_HEIGHT {
+		t.Errorf("text is %v, expected at most %dx%d", img.Bounds(), INSPECTOR_TEXT_WIDTH, VRAM_HEIGHT)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+Helpers for the gpu tests; primitives are sent thru GP0 like the cpu would and checked against vram
+(golden images live in testdata, run the tests with -update to rewrite them after an intended change)
+*/
+var updateGolden = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+/* standalone gpu which draws into the whole of vram */
+func NewTestGPU() *GPU {
+	gpu := NewHeadlessGoStation().GPU
+
+	SendGP0(
+		gpu,
+		0xe3000000,                       // drawing area top left (0,0)
+		0xe4000000|511<<10|1023,          // drawing area bottom right (1023,511)
+		0xe5000000,                       // no drawing offset
+		0xe1000000|TEXTURE_FORMAT_15b<<7, // texpage 0, 15bit
+	)
+
+	return gpu
+}
+
+/* sends words thru GP0 and waits for the gpu (and the render threads) to finish them */
+func SendGP0(gpu *GPU, words ...uint32) {
+	for _, word := range words {
+		gpu.GP0(word)
+	}
+
+	gpu.WaitForIdle()
+	gpu.SyncRenderThreads()
+}
+
+/* GP0(A0h) of a rectangle of halfwords */
+func UploadVRAM(gpu *GPU, x, y, width, height int, pixels []uint16) {
+	words := []uint32{
+		0xa0000000,
+		uint32(y)<<16 | uint32(x),
+		uint32(height)<<16 | uint32(width),
+	}
+
+	for i := 0; i < len(pixels); i += 2 {
+		word := uint32(pixels[i])
+		if i+1 < len(pixels) {
+			word |= uint32(pixels[i+1]) << 16
+		}
+		words = append(words, word)
+	}
+
+	SendGP0(gpu, words...)
+}
+
+func RGB15(r, g, b int) uint16 {
+	return uint16(r&0x1f | (g&0x1f)<<5 | (b&0x1f)<<10)
+}
+
+func VertexWord(x, y int) uint32 {
+	return uint32(uint16(y))<<16 | uint32(uint16(x))
+}
+
+/* area of vram as an image */
+func VRAMImage(gpu *GPU, x, y, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for iy := 0; iy < height; iy += 1 {
+		for ix := 0; ix < width; ix += 1 {
+			img.SetRGBA(ix, iy, gpu.vram.Read15(x+ix, y+iy))
+		}
+	}
+
+	return img
+}
+
+/* compares an image with testdata/<name>.png */
+func CheckGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".png")
+
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WritePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	defer file.Close()
+
+	golden, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("%s is %v, rendered %v", path, golden.Bounds(), img.Bounds())
+	}
+
+	mismatches := 0
+	first := ""
+	for y := 0; y < img.Bounds().Dy(); y += 1 {
+		for x := 0; x < img.Bounds().Dx(); x += 1 {
+			gr, gg, gb, _ := golden.At(x, y).RGBA()
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			if gr != r || gg != g || gb != b {
+				if mismatches == 0 {
+					first = fmt.Sprintf("(%d,%d) is %02x%02x%02x, expected %02x%02x%02x", x, y, r>>8, g>>8, b>>8, gr>>8, gg>>8, gb>>8)
+				}
+				mismatches += 1
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		actual := filepath.Join(os.TempDir(), name+".png")
+		WritePNG(actual, img)
+		t.Errorf("%d pixels differ from %s, first at %s; rendered image is %s", mismatches, path, first, actual)
+	}
+}
+
+/*
+Deterministic mix of everything the renderer does: uploads, fills, copies, flat/gouraud/textured polygons and
+rectangles (semi-transparent, masked, windowed), textures which are drawn over or re-uploaded without
+flushing the texture cache and primitives which texture themselves from where they draw
+*/
+func RandomGP0Stream(seed int64, primitives int) []uint32 {
+	random := rand.New(rand.NewSource(seed))
+
+	coord := func(max int) int { return random.Intn(max) }
+	colour := func() uint32 { return uint32(random.Intn(0x1000000)) }
+	vertex := func() uint32 { return VertexWord(coord(640), coord(480)) }
+	uv := func() uint32 { return uint32(random.Intn(256)) | uint32(random.Intn(256))<<8 }
+
+	// 4bit and 15bit textures live in the texture pages at x=640..1023, cluts right below the frame buffer
+	texPage := func() uint32 {
+		format := []uint32{TEXTURE_FORMAT_4b, TEXTURE_FORMAT_8b, TEXTURE_FORMAT_15b}[random.Intn(3)]
+		return uint32(10+random.Intn(6)) | uint32(random.Intn(2))<<4 | uint32(random.Intn(4))<<5 | format<<7
+	}
+	clut := func() uint32 { return uint32(random.Intn(64)) | uint32(480+random.Intn(32))<<6 }
+
+	upload := func(x, y, width, height int) []uint32 {
+		words := []uint32{0xa0000000, VertexWord(x, y), uint32(height)<<16 | uint32(width)}
+		for i := 0; i < (width*height+1)/2; i += 1 {
+			words = append(words, random.Uint32())
+		}
+		return words
+	}
+
+	stream := upload(640, 0, 384, 512)
+	stream = append(stream, upload(0, 480, 1024, 32)...)
+
+	for i := 0; i < primitives; i += 1 {
+		// semi-transparency and raw texture bits
+		flags := uint32(random.Intn(4)) << 24
+
+		switch random.Intn(17) {
+		case 0:
+			// environment: texpage (with semi-transparency mode), texture window, mask bits
+			stream = append(stream,
+				0xe1000000|texPage(),
+				0xe2000000|uint32(random.Intn(1<<20))*uint32(random.Intn(2)),
+				0xe6000000|uint32(random.Intn(4)),
+			)
+		case 1:
+			stream = append(stream, 0x02000000|colour(), vertex(), uint32(coord(128))<<16|uint32(coord(128)))
+		case 2:
+			stream = append(stream, 0x80000000, VertexWord(coord(1024), coord(512)), VertexWord(coord(1024), coord(512)), uint32(1+coord(64))<<16|uint32(1+coord(64)))
+		case 3:
+			// texture re-uploaded (or drawn over) without flushing the texture cache
+			stream = append(stream, upload(640+coord(320), coord(448), 1+coord(64), 1+coord(64))...)
+			if random.Intn(2) == 0 {
+				stream = append(stream, 0x01000000)
+			}
+		case 4, 5:
+			stream = append(stream, 0x20000000|flags&0x02000000|colour(), vertex(), vertex(), vertex())
+		case 6, 7:
+			stream = append(stream, 0x38000000|flags&0x02000000|colour(), vertex(), colour(), vertex(), colour(), vertex(), colour(), vertex())
+		case 8, 9:
+			stream = append(stream, 0x24000000|flags|colour(), vertex(), clut()<<16|uv(), vertex(), texPage()<<16|uv(), vertex(), uv())
+		case 10, 11:
+			stream = append(stream, 0x3c000000|flags|colour(), vertex(), clut()<<16|uv(), colour(), vertex(), texPage()<<16|uv(), colour(), vertex(), uv(), colour(), vertex(), uv())
+		case 12:
+			stream = append(stream, 0x60000000|flags&0x02000000|colour(), vertex(), uint32(coord(128))<<16|uint32(coord(128)))
+		case 13:
+			stream = append(stream, 0x64000000|flags|colour(), vertex(), clut()<<16|uv(), uint32(coord(128))<<16|uint32(coord(128)))
+		case 14:
+			// textured from the area it draws to
+			x, y := 640+coord(256), coord(256)
+			page := uint32(x/64) | uint32(y/256)<<4 | TEXTURE_FORMAT_15b<<7
+			stream = append(stream,
+				0x2c000000|flags|colour(),
+				VertexWord(x, y), uv(),
+				VertexWord(x+coord(64), y), page<<16|uv(),
+				VertexWord(x, y+coord(64)), uv(),
+				VertexWord(x+coord(64), y+coord(64)), uv(),
+			)
+		case 16:
+			// small texture drawn twice with a re-upload in between, the second one sees stale texels
+			page := texPage()
+			x, y := int(page&0xf)*64, int(page>>4&1)*256
+			draw := []uint32{0xe1000000 | page, 0x65000000, vertex(), clut() << 16, 16<<16 | 16}
+			stream = append(stream, draw...)
+			stream = append(stream, upload(x, y, 16, 16)...)
+			stream = append(stream, draw...)
+		case 15:
+			// drawing area and offset
+			x1, y1 := coord(512), coord(256)
+			stream = append(stream,
+				0xe3000000|uint32(y1)<<10|uint32(x1),
+				0xe4000000|uint32(y1+coord(256))<<10|uint32(x1+coord(512)),
+				0xe5000000|uint32(coord(64)-32)&0x7ff|(uint32(coord(64)-32)&0x7ff)<<11,
+			)
+		}
+	}
+
+	return stream
+}
+
+func CompareVRAM(t *testing.T, gpu *GPU, reference *GPU) {
+	t.Helper()
+
+	mismatches := 0
+	for i := range reference.vram.buffer {
+		if gpu.vram.buffer[i] != reference.vram.buffer[i] {
+			if mismatches == 0 {
+				t.Errorf("vram (%d,%d) is %04x, expected %04x", i%VRAM_WIDTH, i/VRAM_WIDTH, gpu.vram.buffer[i], reference.vram.buffer[i])
+			}
+			mismatches += 1
+		}
+	}
+
+	if mismatches > 0 {
+		t.Errorf("%d pixels differ", mismatches)
+	}
+}
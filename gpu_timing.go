@@ -0,0 +1,180 @@
+package main
+
+/*
+Rough draw time estimation (in gpu clocks) for GP0 commands
+
+The numbers are not exact but they are close enough to make games that poll GPUSTAT (or rely on dma stalling)
+behave; they are loosely based on the measurements in mednafen and the nocash docs:
+- the gpu can write roughly one pixel per clock
+- semi-transparent pixels (and pixels checked against the mask bit) need an additional vram read
+- fetching an uncached texture block from vram takes several clocks
+*/
+const (
+	GPU_CLOCKS_POLYGON_SETUP    = 64
+	GPU_CLOCKS_RECTANGLE_SETUP  = 16
+	GPU_CLOCKS_PER_PIXEL        = 1
+	GPU_CLOCKS_PER_BLEND        = 1
+	GPU_CLOCKS_PER_TEXTURE_MISS = 8
+	GPU_CLOCKS_FILL_SETUP       = 46
+	GPU_CLOCKS_FILL_PER_LINE    = 9
+	GPU_CLOCKS_COPY_PER_PIXEL   = 2
+)
+
+/* clears the statistics of the command which is about to be executed */
+func (gpu *GPU) ResetDrawStats() {
+	gpu.pixelsDrawn = 0
+	gpu.pixelsBlended = 0
+	gpu.texCacheMisses = 0
+	gpu.lastTexBlockX = -1
+	gpu.lastTexBlockY = -1
+}
+
+/*
+Approximates texture cache misses when texture cache emulation is turned off; a miss is counted
+whenever a texel comes from another 8 byte block than the previous one
+*/
+func (gpu *GPU) TrackTextureFetch(x, y int) {
+	blockX := x >> 2 // 4 halfwords per block
+
+	if blockX != gpu.lastTexBlockX || y != gpu.lastTexBlockY {
+		gpu.texCacheMisses += 1
+		gpu.lastTexBlockX = blockX
+		gpu.lastTexBlockY = y
+	}
+}
+
+func (gpu *GPU) EstimatePrimitiveDrawTime() uint32 {
+	clocks := GPU_CLOCKS_RECTANGLE_SETUP
+	if gpu.shape == PRIMITIVE_POLYGON {
+		clocks = GPU_CLOCKS_POLYGON_SETUP
+	}
+
+	clocks += gpu.pixelsDrawn * GPU_CLOCKS_PER_PIXEL
+	clocks += gpu.pixelsBlended * GPU_CLOCKS_PER_BLEND
+	clocks += gpu.texCacheMisses * GPU_CLOCKS_PER_TEXTURE_MISS
+
+	return uint32(clocks)
+}
+
+func (gpu *GPU) EstimateFillDrawTime(width, height int) uint32 {
+	// fills write 8 pixels at once
+	return uint32(GPU_CLOCKS_FILL_SETUP + ((width+7)/8+GPU_CLOCKS_FILL_PER_LINE)*height)
+}
+
+func (gpu *GPU) EstimateCopyDrawTime(width, height int) uint32 {
+	// each pixel is read then written
+	return uint32(width * height * GPU_CLOCKS_COPY_PER_PIXEL)
+}
+
+/*
+Makes the gpu busy for the given amount of gpu clocks; gpu clock is the cpu clock multiplied by 11/7
+*/
+func (gpu *GPU) ChargeDrawTime(gpuClocks uint32) {
+	gpu.busyCycles += (gpuClocks*7 + 10) / 11 // round up
+}
+
+/*
+Executes the queued GP0 words until the gpu becomes busy or the queue runs dry
+*/
+func (gpu *GPU) ProcessCommandQueue() {
+	for gpu.busyCycles == 0 && !gpu.cmdQueue.Empty() {
+		gpu.GP0Execute(gpu.cmdQueue.Pop())
+	}
+}
+
+/*
+Runs the gpu ahead until there is a free slot in the command fifo.
+Returns the number of cpu cycles that the writer (cpu or dma) has to stall; the gpu is already synced
+over them so the stall must be passed to GoStation::Stall, otherwise the gpu ends up ahead of the console.
+*/
+func (gpu *GPU) WaitForFIFOSpace() uint32 {
+	gpu.RunUntil(gpu.Core.Scheduler.Now())
+	start := gpu.syncedAt
+
+	for gpu.cmdQueue.Full() {
+		gpu.RunBusyCycles()
+	}
+
+	return uint32(gpu.syncedAt - start)
+}
+
+/*
+Runs the gpu ahead until all queued commands are done.
+Returns the number of cpu cycles that the caller has to stall (see GPU::WaitForFIFOSpace).
+*/
+func (gpu *GPU) WaitForIdle() uint32 {
+	gpu.RunUntil(gpu.Core.Scheduler.Now())
+	start := gpu.syncedAt
+
+	for gpu.busyCycles > 0 || !gpu.cmdQueue.Empty() {
+		gpu.RunBusyCycles()
+	}
+
+	return uint32(gpu.syncedAt - start)
+}
+
+/* runs the gpu until it is done with the command it is drawing and has started the next queued ones */
+func (gpu *GPU) RunBusyCycles() {
+	if gpu.busyCycles == 0 {
+		gpu.ProcessCommandQueue()
+		return
+	}
+
+	gpu.RunUntil(gpu.syncedAt + uint64(gpu.busyCycles))
+}
+
+/* GPUSTAT.26 */
+func (gpu *GPU) ReadyToReceiveCommand() bool {
+	return gpu.busyCycles == 0 && gpu.cmdQueue.Empty() && !gpu.fifoActive
+}
+
+/* GPUSTAT.27 */
+func (gpu *GPU) ReadyToSendVRAM() bool {
+	return gpu.mode == MODE_VramtoCPUBlit && gpu.cmdQueue.Empty()
+}
+
+/* GPUSTAT.28 */
+func (gpu *GPU) ReadyToReceiveDMA() bool {
+	return !gpu.cmdQueue.Full()
+}
+
+/* cpu cycles until the gpu finishes the current command or starts the next scanline (whatever comes first) */
+func (gpu *GPU) CyclesUntilNextEvent() uint32 {
+	var cycles uint32 = 1
+	if gpu.videoCyclesx7 < gpu.videoCyclesPerScanlinex7 {
+		cycles = (gpu.videoCyclesPerScanlinex7 - gpu.videoCyclesx7 + 10) / 11 // round up
+	}
+
+	if gpu.busyCycles > 0 && gpu.busyCycles < cycles {
+		cycles = gpu.busyCycles
+	}
+
+	return cycles
+}
+
+/*
+Catches the gpu up with the rest of the console; called before its registers are accessed and by its
+event. GPU::Step handles at most one scanline and one command at a time so the gpu is stepped in chunks
+which end at those.
+*/
+func (gpu *GPU) Sync() {
+	gpu.RunUntil(gpu.Core.Scheduler.Now())
+	gpu.ScheduleEvent()
+}
+
+/* steps the gpu up to the given time; it can be ahead of the console after a stall (nothing to do then) */
+func (gpu *GPU) RunUntil(time uint64) {
+	for gpu.syncedAt < time {
+		cycles := uint64(gpu.CyclesUntilNextEvent())
+		if time-gpu.syncedAt < cycles {
+			cycles = time - gpu.syncedAt
+		}
+
+		gpu.Step(uint32(cycles))
+		gpu.syncedAt += cycles
+	}
+}
+
+func (gpu *GPU) ScheduleEvent() {
+	gpu.Core.Scheduler.ScheduleAt(gpu.event, gpu.syncedAt+uint64(gpu.CyclesUntilNextEvent()))
+}
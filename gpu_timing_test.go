@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+)
+
+/* GP0(02h) of 64x64 pixels: 46 + (64/8 + 9) * 64 gpu clocks, which are 722 cpu cycles (rounded up) */
+var testFill = []uint32{0x02000000, VertexWord(0, 0), 64<<16 | 64}
+
+const testFillCycles = 722
+
+/* writes the words to GP0 at the current time like the cpu does (syncing the gpu first) */
+func WriteGP0(gpu *GPU, words ...uint32) {
+	for _, word := range words {
+		gpu.Write32(0x1f801810, word)
+	}
+}
+
+/* lets the given number of cycles pass on the console (including the ones the cpu was stalled for) */
+func PassCycles(gostation *GoStation, cycles uint32) {
+	gostation.Scheduler.Advance(cycles + gostation.stallCycles)
+	gostation.stallCycles = 0
+}
+
+/* gpu which is busy with a fill and has 5 more fills and a word of a 6th one queued (a full fifo) */
+func NewBusyTestGPU(t *testing.T) *GPU {
+	gpu := NewTestGPU()
+	gpu.Sync()
+
+	for i := 0; i < 6; i += 1 {
+		WriteGP0(gpu, testFill...)
+	}
+	WriteGP0(gpu, testFill[0])
+
+	if gpu.busyCycles != testFillCycles || !gpu.cmdQueue.Full() {
+		t.Fatalf("gpu is busy for %d cycles with %d words queued, expected %d and a full fifo", gpu.busyCycles, gpu.cmdQueue.Size(), testFillCycles)
+	}
+
+	return gpu
+}
+
+func TestFIFOFullStalls(t *testing.T) {
+	gpu := NewBusyTestGPU(t)
+	gostation := gpu.Core
+
+	// the writer waits for the first fill to be done, which makes room for the next one
+	WriteGP0(gpu, testFill[1])
+
+	if gostation.stallCycles != testFillCycles {
+		t.Errorf("stalled for %d cycles, expected %d", gostation.stallCycles, testFillCycles)
+	}
+
+	// the stalled cycles are only spent once: the second fill starts after them and takes as long as the first
+	PassCycles(gostation, 0)
+	gpu.Sync()
+
+	if gpu.busyCycles != testFillCycles {
+		t.Errorf("second fill has %d cycles left, expected %d", gpu.busyCycles, testFillCycles)
+	}
+	if size := gpu.cmdQueue.Size(); size != 14 {
+		t.Errorf("%d words queued, expected 14", size)
+	}
+
+	// everything queued is done 5 fills later
+	stalled := gpu.WaitForIdle()
+	if stalled != 5*testFillCycles {
+		t.Errorf("waited %d cycles for the gpu to be idle, expected %d", stalled, 5*testFillCycles)
+	}
+}
+
+func TestFIFOFullStallsDMA(t *testing.T) {
+	gpu := NewBusyTestGPU(t)
+	gostation := gpu.Core
+	start := gostation.Scheduler.Now()
+
+	// dma writes all its words at once; each one waits for the fills before it
+	for i := 0; i < 3*4; i += 1 {
+		gpu.GP0(testFill[(i+1)%3])
+	}
+
+	if gostation.stallCycles != 4*testFillCycles {
+		t.Errorf("stalled for %d cycles, expected %d", gostation.stallCycles, 4*testFillCycles)
+	}
+	if gpu.syncedAt != start+4*testFillCycles {
+		t.Errorf("gpu is at %d, expected it to have run over the stall up to %d", gpu.syncedAt, start+4*testFillCycles)
+	}
+
+	PassCycles(gostation, 0)
+	gpu.Sync()
+
+	if gpu.busyCycles != testFillCycles {
+		t.Errorf("fill has %d cycles left, expected %d", gpu.busyCycles, testFillCycles)
+	}
+}
+
+func TestGPUSTATReadyBits(t *testing.T) {
+	gpu := NewTestGPU()
+	gostation := gpu.Core
+	gpu.Sync()
+
+	status := func() (bool, bool) {
+		status := gpu.Read32(0x1f801814)
+		return TestBit(status, 26), TestBit(status, 28)
+	}
+	check := func(what string, command, dma bool) {
+		t.Helper()
+
+		if c, d := status(); c != command || d != dma {
+			t.Errorf("%s: ready to receive command %t, dma %t; expected %t, %t", what, c, d, command, dma)
+		}
+	}
+
+	check("idle", true, true)
+
+	WriteGP0(gpu, testFill[0])
+	check("in the middle of a command", false, true)
+
+	WriteGP0(gpu, testFill[1:]...)
+	check("drawing", false, true)
+
+	PassCycles(gostation, testFillCycles-1)
+	check("a cycle before the end of the fill", false, true)
+	PassCycles(gostation, 1)
+	check("fill done", true, true)
+
+	for i := 0; i < 6; i += 1 {
+		WriteGP0(gpu, testFill...)
+	}
+	WriteGP0(gpu, testFill[0])
+	check("fifo full", false, false)
+
+	PassCycles(gostation, testFillCycles)
+	check("first fill done", false, true)
+
+	// the 7th fill is waiting for the rest of its words
+	PassCycles(gostation, 5*testFillCycles)
+	check("fills done", false, true)
+	WriteGP0(gpu, testFill[1:]...)
+	PassCycles(gostation, 2*testFillCycles)
+	check("all done", true, true)
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+/*
+GPU command traces
+
+A trace holds everything the gpu got from the outside while it was being recorded so that a frame can be
+reproduced (and renderer bugs bisected) without running the cpu:
+
+	header   "GSGPUTR1"
+	vram     1024*512 halfwords (little endian); contents of vram when the recording started
+	timing   scanline, videoCyclesx7, field, completedField, flags (5 words; flags: 0=interlace, 1=interlaceOdd, 2=vblank)
+	records  1 byte type + 1 word argument each
+
+The gpu state at the start is recorded as GP0/GP1 commands which set it up again (see GPU::StateCommands).
+VRAM uploads (GP0(A0h)) are part of the GP0 words. Every GPU::Step is recorded so that the replay steps the
+gpu exactly like the emulator did (which matters for the command fifo and for interlacing); since there is
+one per instruction, runs of steps with the same amount of cycles are stored as a TRACE_STEP followed by a
+TRACE_REPEAT.
+*/
+const GPU_TRACE_MAGIC = "GSGPUTR1"
+
+const (
+	TRACE_GP0     = iota /* word written to GP0 (by the cpu or by dma) */
+	TRACE_GP1            /* word written to GP1 */
+	TRACE_GPUREAD        /* GPUREAD was read (the argument is unused) */
+	TRACE_STEP           /* the gpu was stepped for this many cpu cycles */
+	TRACE_REPEAT         /* the previous step was repeated this many more times */
+	TRACE_FRAME          /* the emulator finished a frame (the argument is the frame number) */
+)
+
+type GPUTraceRecorder struct {
+	file    *os.File
+	writer  *bufio.Writer
+	started bool /* waiting for the gpu to become idle otherwise */
+	frames  uint32
+
+	/* steps not written yet */
+	stepCycles uint32
+	steps      uint32
+}
+
+/*
+Starts recording a trace into a file; the recording actually begins at the end of the first frame where
+the gpu isn't in the middle of a command
+*/
+func (gpu *GPU) StartTrace(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	gpu.trace = &GPUTraceRecorder{
+		file,
+		bufio.NewWriter(file),
+		false,
+		0,
+		0,
+		0,
+	}
+
+	return nil
+}
+
+func (gpu *GPU) StopTrace() {
+	if gpu.trace == nil {
+		return
+	}
+
+	gpu.trace.Flush()
+	gpu.trace.writer.Flush()
+	gpu.trace.file.Close()
+
+	fmt.Printf("[GPU::StopTrace] recorded %d frames\n", gpu.trace.frames)
+
+	gpu.trace = nil
+}
+
+func (gpu *GPU) TraceStep(cycles uint32) {
+	trace := gpu.trace
+	if trace == nil || !trace.started {
+		return
+	}
+
+	if trace.steps > 0 && trace.stepCycles != cycles {
+		trace.Flush()
+	}
+
+	trace.stepCycles = cycles
+	trace.steps += 1
+}
+
+func (gpu *GPU) TraceWord(kind uint8, data uint32) {
+	if gpu.trace != nil && gpu.trace.started {
+		gpu.trace.Flush()
+		gpu.trace.Write(kind, data)
+	}
+}
+
+/* called by the emulator at the end of each frame */
+func (gpu *GPU) TraceFrame() {
+	trace := gpu.trace
+	if trace == nil {
+		return
+	}
+
+	if !trace.started {
+		if gpu.mode != MODE_NORMAL || gpu.fifoActive || !gpu.cmdQueue.Empty() {
+			// try again next frame
+			return
+		}
+
+		gpu.WriteTraceHeader()
+		trace.started = true
+		return
+	}
+
+	trace.Flush()
+	trace.Write(TRACE_FRAME, trace.frames)
+	trace.frames += 1
+}
+
+func (gpu *GPU) WriteTraceHeader() {
+	writer := gpu.trace.writer
+
+	gpu.SyncRenderThreads()
+	gpu.renderer.SyncVRAM(gpu.vram)
+
+	writer.WriteString(GPU_TRACE_MAGIC)
+	binary.Write(writer, binary.LittleEndian, gpu.vram.buffer)
+
+	var flags uint32
+	ModifyBit(&flags, 0, gpu.interlace)
+	ModifyBit(&flags, 1, gpu.interlaceOdd)
+	ModifyBit(&flags, 2, gpu.vblank)
+
+	timing := []uint32{
+		gpu.scanline,
+		gpu.videoCyclesx7,
+		uint32(gpu.field),
+		uint32(gpu.completedField),
+		flags,
+	}
+	binary.Write(writer, binary.LittleEndian, timing)
+
+	gp0, gp1 := gpu.StateCommands()
+	for _, data := range gp1 {
+		gpu.trace.Write(TRACE_GP1, data)
+	}
+	for _, data := range gp0 {
+		gpu.trace.Write(TRACE_GP0, data)
+	}
+}
+
+/* writes pending steps */
+func (trace *GPUTraceRecorder) Flush() {
+	if trace.steps == 0 {
+		return
+	}
+
+	trace.Write(TRACE_STEP, trace.stepCycles)
+	if trace.steps > 1 {
+		trace.Write(TRACE_REPEAT, trace.steps-1)
+	}
+
+	trace.steps = 0
+}
+
+func (trace *GPUTraceRecorder) Write(kind uint8, data uint32) {
+	var record [5]byte
+
+	record[0] = kind
+	binary.LittleEndian.PutUint32(record[1:], data)
+
+	trace.writer.Write(record[:])
+}
+
+/*
+Returns GP0 and GP1 commands which bring another gpu into the same drawing and display state
+*/
+func (gpu *GPU) StateCommands() ([]uint32, []uint32) {
+	var e1 uint32 = 0xe1000000
+	PackRange(&e1, 0, uint32(gpu.txBase), 4)
+	PackRange(&e1, 4, uint32(gpu.tyBase), 1)
+	PackRange(&e1, 5, uint32(gpu.semiTransparency), 2)
+	PackRange(&e1, 7, uint32(gpu.textureFormat), 2)
+	ModifyBit(&e1, 9, gpu.dilthering)
+	ModifyBit(&e1, 10, gpu.drawToDisplay)
+	ModifyBit(&e1, 11, gpu.textureDisable)
+	ModifyBit(&e1, 12, gpu.rectTextureXFlip)
+	ModifyBit(&e1, 13, gpu.rectTextureYFlip)
+
+	var e2 uint32 = 0xe2000000
+	PackRange(&e2, 0, uint32(gpu.texWindowMaskX), 5)
+	PackRange(&e2, 5, uint32(gpu.texWindowMaskY), 5)
+	PackRange(&e2, 10, uint32(gpu.texWindowOffsetX), 5)
+	PackRange(&e2, 15, uint32(gpu.texWindowOffsetY), 5)
+
+	var e3 uint32 = 0xe3000000
+	PackRange(&e3, 0, uint32(gpu.drawingAreaX1), 10)
+	PackRange(&e3, 10, uint32(gpu.drawingAreaY1), 10)
+
+	var e4 uint32 = 0xe4000000
+	PackRange(&e4, 0, uint32(gpu.drawingAreaX2), 10)
+	PackRange(&e4, 10, uint32(gpu.drawingAreaY2), 10)
+
+	var e5 uint32 = 0xe5000000
+	PackRange(&e5, 0, uint32(gpu.drawingXOffset), 11)
+	PackRange(&e5, 11, uint32(gpu.drawingYOffset), 11)
+
+	var e6 uint32 = 0xe6000000
+	ModifyBit(&e6, 0, gpu.setMaskBit)
+	ModifyBit(&e6, 1, gpu.drawUnmaskedPixels)
+
+	var displayEnable uint32 = 0x03000000
+	ModifyBit(&displayEnable, 0, gpu.displayDisable)
+
+	var dmaDirection uint32 = 0x04000000
+	PackRange(&dmaDirection, 0, uint32(gpu.dmaDirection), 2)
+
+	var displayStart uint32 = 0x05000000
+	PackRange(&displayStart, 0, uint32(gpu.displayVramStartX), 10)
+	PackRange(&displayStart, 10, uint32(gpu.displayVramStartY), 9)
+
+	var horizRange uint32 = 0x06000000
+	PackRange(&horizRange, 0, gpu.displayHorizX1x7/7, 12)
+	PackRange(&horizRange, 12, gpu.displayHorizX2x7/7, 12)
+
+	var vertRange uint32 = 0x07000000
+	PackRange(&vertRange, 0, gpu.displayVertY1, 10)
+	PackRange(&vertRange, 10, gpu.displayVertY2, 10)
+
+	var displayMode uint32 = 0x08000000
+	PackRange(&displayMode, 0, uint32(gpu.hr1), 2)
+	ModifyBit(&displayMode, 2, gpu.vertRes)
+	ModifyBit(&displayMode, 3, gpu.PALMode)
+	ModifyBit(&displayMode, 4, gpu.displayColourDepth)
+	ModifyBit(&displayMode, 5, gpu.verticalInterlace)
+	ModifyBit(&displayMode, 6, gpu.hr2)
+	ModifyBit(&displayMode, 7, gpu.reverseFlag)
+
+	var textureDisable uint32 = 0x09000000
+	ModifyBit(&textureDisable, 0, gpu.textureDisableAllowed)
+
+	gp0 := []uint32{e1, e2, e3, e4, e5, e6}
+	gp1 := []uint32{displayEnable, dmaDirection, displayStart, horizRange, vertRange, displayMode, textureDisable}
+
+	return gp0, gp1
+}
+
+/*
+Feeds a trace into a standalone gpu and writes a png of every frame into outDir; frames show the
+display area unless wholeVRAM is set
+*/
+func ReplayGPUTrace(path string, outDir string, wholeVRAM bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	gpu := NewHeadlessGoStation().GPU
+	display := NewDisplayOutput(DEINTERLACE_WEAVE)
+
+	err = gpu.ReplayTrace(bufio.NewReader(file), func(frame uint32) error {
+		path := filepath.Join(outDir, fmt.Sprintf("frame_%05d.png", frame))
+
+		if wholeVRAM {
+			return WritePNG(path, gpu.vram.Image())
+		}
+
+		return WritePNG(path, display.Render(gpu))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+/*
+Replays a trace on the gpu; frame is called (with vram up to date) at the end of every recorded frame
+*/
+func (gpu *GPU) ReplayTrace(reader io.Reader, frame func(frame uint32) error) error {
+	magic := make([]byte, len(GPU_TRACE_MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != GPU_TRACE_MAGIC {
+		return fmt.Errorf("not a gpu trace")
+	}
+
+	if err := binary.Read(reader, binary.LittleEndian, gpu.vram.buffer); err != nil {
+		return err
+	}
+
+	timing := make([]uint32, 5)
+	if err := binary.Read(reader, binary.LittleEndian, timing); err != nil {
+		return err
+	}
+
+	timingRestored := false
+	var stepCycles uint32
+
+	var record [5]byte
+	for {
+		if _, err := io.ReadFull(reader, record[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		data := binary.LittleEndian.Uint32(record[1:])
+
+		if !timingRestored && record[0] != TRACE_GP1 {
+			// GP1(08h) in the state commands resets the field; put the recorded timing back afterwards
+			gpu.scanline = timing[0]
+			gpu.videoCyclesx7 = timing[1]
+			gpu.field = int(timing[2])
+			gpu.completedField = int(timing[3])
+			gpu.interlace = TestBit(timing[4], 0)
+			gpu.interlaceOdd = TestBit(timing[4], 1)
+			gpu.vblank = TestBit(timing[4], 2)
+			timingRestored = true
+		}
+
+		switch record[0] {
+		case TRACE_GP0:
+			gpu.GP0(data)
+		case TRACE_GP1:
+			gpu.GP1(data)
+		case TRACE_GPUREAD:
+			gpu.GPUREAD()
+		case TRACE_STEP:
+			stepCycles = data
+			gpu.Step(stepCycles)
+		case TRACE_REPEAT:
+			for i := uint32(0); i < data; i += 1 {
+				gpu.Step(stepCycles)
+			}
+		case TRACE_FRAME:
+			gpu.SyncRenderThreads()
+			gpu.renderer.SyncVRAM(gpu.vram)
+
+			if frame != nil {
+				if err := frame(data); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unknown record type %d", record[0])
+		}
+	}
+}
+
+/*
+Just enough of a console to run the gpu on its own (eg. for replaying traces)
+*/
+func NewHeadlessGoStation() *GoStation {
+	gostation := &GoStation{}
+
+	gostation.Scheduler = NewScheduler()
+	gostation.PGXP = NewPGXP()
+	gostation.Interrupts = NewInterrupts(gostation)
+	gostation.GPU = NewGPU(gostation)
+	gostation.cyclesPerFrame = CPU_CYCLES_PER_SEC / 60
+
+	return gostation
+}
@@ -0,0 +1,162 @@
+package main
+
+/*
+https://psx-spx.consoledev.net/memorycontrol/#fffe0130h-cache-control-rw
+
+	0-1   Unknown                                 (R/W)
+	2     Tag Test Mode (isolated stores set tags) (R/W)
+	3     Scratchpad Enable 1 (needs bit 7 too)   (R/W)
+	4-6   Unknown                                 (R/W)
+	7     Scratchpad Enable 2 (needs bit 3 too)   (R/W)
+	8     Unknown                                 (R/W)
+	9     Crash (0=Normal, 1=Crash if code-cache enabled) (R/W)
+	10    Unknown                                 (R/W)
+	11    Code-Cache Enable (0=Disable, 1=Enable) (R/W)
+	12-31 Unknown                                 (R/W)
+
+The instruction cache is 4KiB direct mapped, 256 lines of 16 bytes (4 words). Each line has a tag (the physical address of the line)
+and a valid bit per word. Only code fetched from KUSEG and KSEG0 goes thru the cache, KSEG1 is uncached.
+
+On a miss the line is filled from the missing word up to the end of the line; the first word costs a
+normal bus access and each following word one more cycle (burst). Hits don't take any bus cycles.
+
+Writes to ram don't touch the cache, so code which gets modified has to be flushed by software: the
+BIOS isolates the cache (cop0r12 bit 16) and while it is isolated, stores go to the cache instead of
+memory. With tag test mode set a store invalidates the line it hits (and sets its tag), otherwise it
+writes the data word.
+*/
+const (
+	CACHE_CONTROL = 0xfffe0130
+
+	ICACHE_LINES      = 256
+	ICACHE_LINE_WORDS = 4
+	ICACHE_TAG_MASK   = 0xfffff000 /* physical address bits 12 and up */
+
+	ICACHE_BURST_CYCLES = 1 /* per word after the first one of a line fill */
+)
+
+type ICache struct {
+	Core *GoStation
+
+	control uint32 /* FFFE0130h */
+
+	tags  [ICACHE_LINES]uint32
+	valid [ICACHE_LINES]uint8 /* bit per word */
+	data  [ICACHE_LINES * ICACHE_LINE_WORDS]uint32
+
+	isolatedStores bool /* stores went into the cache since it got isolated */
+}
+
+func NewICache(core *GoStation) *ICache {
+	return &ICache{
+		core,
+		0,
+		[ICACHE_LINES]uint32{},
+		[ICACHE_LINES]uint8{},
+		[ICACHE_LINES * ICACHE_LINE_WORDS]uint32{},
+		false,
+	}
+}
+
+func (icache *ICache) Contains(address uint32) bool {
+	return address == CACHE_CONTROL
+}
+
+func (icache *ICache) Read32(address uint32) uint32 {
+	return icache.control
+}
+
+func (icache *ICache) Write32(address uint32, data uint32) {
+	icache.control = data
+}
+
+func (icache *ICache) Enabled() bool {
+	return TestBit(icache.control, 11)
+}
+
+func (icache *ICache) TagTestMode() bool {
+	return TestBit(icache.control, 2)
+}
+
+/* whether fetches from the (virtual) address go thru the cache */
+func (icache *ICache) Caches(address uint32) bool {
+	return icache.Enabled() && address < 0xa0000000 // KUSEG and KSEG0
+}
+
+func ICacheLine(address uint32) int {
+	return int(address>>4) & (ICACHE_LINES - 1)
+}
+
+func ICacheWord(address uint32) int {
+	return int(address>>2) & (ICACHE_LINE_WORDS - 1)
+}
+
+/* fetches an instruction word from a physical address, filling the line on a miss */
+func (icache *ICache) Fetch(address uint32) uint32 {
+	line := ICacheLine(address)
+	word := ICacheWord(address)
+	tag := address & ICACHE_TAG_MASK
+
+	if icache.tags[line] == tag && TestBit(uint32(icache.valid[line]), word) {
+		return icache.data[line*ICACHE_LINE_WORDS+word]
+	}
+
+	if icache.tags[line] != tag {
+		icache.tags[line] = tag
+		icache.valid[line] = 0
+	}
+
+	bus := icache.Core.Bus
+	bus.Charge(address, ACCESS_WORD, false)
+
+	lineAddress := address &^ 0xf
+	for i := word; i < ICACHE_LINE_WORDS; i += 1 {
+		if i > word {
+			bus.cycles += ICACHE_BURST_CYCLES
+		}
+
+		icache.data[line*ICACHE_LINE_WORDS+i] = bus.Read32(lineAddress + uint32(i*4))
+		icache.valid[line] |= 1 << i
+	}
+
+	if bus.fault {
+		// bus error, the cpu raises an IBE
+		icache.valid[line] = 0
+		return 0
+	}
+
+	return icache.data[line*ICACHE_LINE_WORDS+word]
+}
+
+/* store while the cache is isolated (see cop0r12 bit 16) */
+func (icache *ICache) IsolatedWrite(address uint32, data uint32) {
+	line := ICacheLine(address)
+	icache.isolatedStores = true
+
+	if icache.TagTestMode() {
+		icache.tags[line] = address & ICACHE_TAG_MASK
+		icache.valid[line] = 0
+		return
+	}
+
+	icache.data[line*ICACHE_LINE_WORDS+ICacheWord(address)] = data
+}
+
+/*
+Called when cop0r12 bit 16 gets cleared. Stores into the isolated cache are how the BIOS flushes it after
+loading code, so decoded blocks may be stale now; they are thrown away once instead of on every store.
+*/
+func (icache *ICache) EndIsolation() {
+	if blocks := icache.Core.CPU.blocks; blocks != nil && icache.isolatedStores {
+		blocks.Flush()
+	}
+
+	icache.isolatedStores = false
+}
+
+/* invalidates every line (eg. after code got loaded behind the BIOS' back) */
+func (icache *ICache) Flush() {
+	for line := range icache.valid {
+		icache.valid[line] = 0
+	}
+}
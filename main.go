@@ -1,84 +1,388 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"unsafe"
-
-	"github.com/veandco/go-sdl2/sdl"
-)
-
-func run() int {
-	var window *sdl.Window
-	var renderer *sdl.Renderer
-	var texture *sdl.Texture
-	var err error
-
-	sdl.Init(sdl.INIT_EVERYTHING)
-	defer sdl.Quit()
-
-	window, err = sdl.CreateWindow("GOSTATION", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		VRAM_WIDTH, VRAM_HEIGHT, sdl.WINDOW_SHOWN)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create window: %s\n", err)
-		return 1
-	}
-	defer window.Destroy()
-
-	renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create renderer: %s\n", err)
-		return 2
-	}
-	defer renderer.Destroy()
-
-	texture, err = renderer.CreateTexture(sdl.PIXELFORMAT_BGR555, sdl.TEXTUREACCESS_STREAMING, VRAM_WIDTH, VRAM_HEIGHT)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create texture: %s\n", err)
-		return 4
-	}
-	defer texture.Destroy()
-
-	gopsx := NewGoStation("roms/SCPH1001.BIN")
-	// gopsx.LoadExecutable("roms/tests/psxtest_cpu/psxtest_cpu.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/HelloWorld/16BPP/HelloWorld16BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTexturePolygon/CLUT4BPP/RenderTexturePolygonCLUT4BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/ImageLoad/ImageLoad.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderPolygon/RenderPolygon16BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderRectangle/RenderRectangle16BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTextureRectangle/CLUT4BPP/RenderTextureRectangleCLUT4BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTextureRectangle/15BPP/RenderTextureRectangle15BPP.exe")
-	// gopsx.LoadExecutable("roms/tests/PSX/Demo/PSXNICCC/PSXNICCC.exe")
-	// gopsx.LoadExecutable("roms/tests/hello.exe")
-
-	var event sdl.Event
-	var running bool = true
-
-	for running {
-		for event = sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch t := event.(type) {
-			case *sdl.QuitEvent:
-				running = false
-			case *sdl.KeyboardEvent:
-				keyCode := t.Keysym.Sym
-				if keyCode == 113 {
-					// use q to toggle cpu logging
-					gopsx.log = !gopsx.log
-				}
-			}
-		}
-
-		gopsx.Update()
-
-		texture.Update(nil, unsafe.Pointer(&gopsx.GPU.vram.buffer[0]), VRAM_WIDTH*2)
-
-		renderer.Copy(texture, nil, nil)
-		renderer.Present()
-	}
-
-	return 0
-}
-
-func main() {
-	os.Exit(run())
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func run() int {
+	renderThreads := flag.Int("render-threads", 0, "number of software render threads (0 = render on the emulation thread)")
+	upscale := flag.Int("upscale", 1, "internal resolution multiplier (1 = native resolution)")
+	recordTrace := flag.String("record-trace", "", "record all gpu commands into this file")
+	replayTrace := flag.String("replay-trace", "", "replay a gpu trace without running the emulator and save each frame as png")
+	replayOut := flag.String("replay-out", "frames", "directory for the frames of -replay-trace")
+	replayVRAM := flag.Bool("replay-vram", false, "save the whole vram instead of the display area when replaying")
+	exe := flag.String("exe", "", "executable to load after the bios has started")
+	headless := flag.Bool("headless", false, "run without a window (see -frames and -dump-frames)")
+	frames := flag.Int("frames", 600, "number of frames to run with -headless")
+	dumpFrames := flag.String("dump-frames", "", "save frames as png into this directory when running with -headless")
+	dumpEvery := flag.Int("dump-every", 1, "save only every nth frame with -dump-frames")
+	dumpVRAM := flag.Bool("dump-vram", false, "save the whole vram instead of the display area with -dump-frames")
+	recordVideo := flag.String("record-video", "", "record the display output into this avi file")
+	videoCodec := flag.String("video-codec", "mjpeg", "codec for -record-video and the r hotkey (mjpeg or raw)")
+	cpuEngine := flag.String("cpu-engine", "interpreter", "how to run the cpu: interpreter, cached (decoded blocks) or differential (cached, checked against the interpreter after every instruction)")
+	biosTrace := flag.String("bios-trace", "", "trace kernel calls with their arguments and return values into this file (- for stdout)")
+	biosTraceFilter := flag.String("bios-trace-filter", "", "comma separated tables (A, B, C, SYS), functions (A3F) or names to trace; a leading - excludes them")
+	textureCache := flag.Bool("texture-cache", false, "emulate the gpu's texture cache (games which don't flush it after uploading textures show stale texels like on a real console); t toggles it")
+	busPolicy := flag.String("bus-policy", "lenient", "what to do on unmapped memory accesses: lenient (emulate bus errors/open bus and log) or strict (panic)")
+	flag.Parse()
+
+	codec := AVI_CODEC_MJPEG
+	if *videoCodec == "raw" {
+		codec = AVI_CODEC_RAW
+	}
+
+	if *replayTrace != "" {
+		if err := ReplayGPUTrace(*replayTrace, *replayOut, *replayVRAM); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to replay trace: %s\n", err)
+			return 5
+		}
+		return 0
+	}
+
+	if *upscale < 1 {
+		*upscale = 1
+	}
+
+	bios := "roms/SCPH1001.BIN"
+	gopsx := NewGoStation(bios)
+	if *upscale > 1 {
+		gopsx.GPU.SetRenderer(NewUpscalingRenderer(*upscale, gopsx.GPU.vram))
+	}
+	gopsx.GPU.SetTextureCacheEnabled(*textureCache)
+	if *busPolicy == "strict" {
+		gopsx.Bus.SetPolicy(BUS_POLICY_STRICT)
+	}
+	if *cpuEngine == "cached" || *cpuEngine == "differential" {
+		gopsx.EnableBlockCache()
+	}
+	if *cpuEngine == "differential" {
+		gopsx.EnableDifferential(bios)
+	}
+	defer gopsx.Bus.DumpUnhandledAccesses(os.Stdout)
+	gopsx.GPU.StartRenderThreads(*renderThreads)
+	if *recordTrace != "" {
+		if err := gopsx.GPU.StartTrace(*recordTrace); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record trace: %s\n", err)
+			return 5
+		}
+		defer gopsx.GPU.StopTrace()
+	}
+	if *biosTrace != "" {
+		if err := gopsx.StartBIOSTrace(*biosTrace, *biosTraceFilter); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to trace BIOS calls: %s\n", err)
+			return 5
+		}
+		defer gopsx.StopBIOSTrace()
+	}
+	if *exe != "" {
+		gopsx.LoadExecutable(*exe)
+	}
+	if *recordVideo != "" {
+		if err := gopsx.StartVideo(*recordVideo, codec); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record video: %s\n", err)
+			return 5
+		}
+	}
+	defer gopsx.StopVideo()
+	// gopsx.LoadExecutable("roms/tests/psxtest_cpu/psxtest_cpu.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/HelloWorld/16BPP/HelloWorld16BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTexturePolygon/CLUT4BPP/RenderTexturePolygonCLUT4BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/ImageLoad/ImageLoad.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderPolygon/RenderPolygon16BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderRectangle/RenderRectangle16BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTextureRectangle/CLUT4BPP/RenderTextureRectangleCLUT4BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/GPU/16BPP/RenderTextureRectangle/15BPP/RenderTextureRectangle15BPP.exe")
+	// gopsx.LoadExecutable("roms/tests/PSX/Demo/PSXNICCC/PSXNICCC.exe")
+	// gopsx.LoadExecutable("roms/tests/hello.exe")
+
+	if *headless {
+		if err := gopsx.RunHeadless(*frames, *dumpFrames, *dumpEvery, *dumpVRAM); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to dump frames: %s\n", err)
+			return 5
+		}
+		return 0
+	}
+
+	var window *sdl.Window
+	var renderer *sdl.Renderer
+	var texture *sdl.Texture
+	var displayTexture *sdl.Texture
+	var inspectorTexture *sdl.Texture
+	var err error
+
+	sdl.Init(sdl.INIT_EVERYTHING)
+	defer sdl.Quit()
+
+	window, err = sdl.CreateWindow("GOSTATION", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		VRAM_WIDTH, VRAM_HEIGHT, sdl.WINDOW_SHOWN)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create window: %s\n", err)
+		return 1
+	}
+	defer window.Destroy()
+
+	renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create renderer: %s\n", err)
+		return 2
+	}
+	defer renderer.Destroy()
+
+	texture, err = renderer.CreateTexture(sdl.PIXELFORMAT_BGR555, sdl.TEXTUREACCESS_STREAMING, VRAM_WIDTH, VRAM_HEIGHT)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create texture: %s\n", err)
+		return 4
+	}
+	defer texture.Destroy()
+
+	// ABGR8888 has the same memory layout as image.RGBA (r, g, b, a bytes) on little endian machines
+	displayTexture, err = renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(DISPLAY_MAX_WIDTH**upscale), int32(DISPLAY_MAX_HEIGHT**upscale))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create texture: %s\n", err)
+		return 4
+	}
+	defer displayTexture.Destroy()
+
+	inspectorTexture, err = renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, INSPECTOR_TEXT_WIDTH, VRAM_HEIGHT)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create texture: %s\n", err)
+		return 4
+	}
+	defer inspectorTexture.Destroy()
+	inspectorTexture.SetBlendMode(sdl.BLENDMODE_BLEND)
+
+	display := NewDisplayOutput(DEINTERLACE_WEAVE)
+	showDisplay := false
+	var inspected []*PrimitiveRecord
+	var inspectedText *image.RGBA
+	inspectX, inspectY := -1, -1
+	textureView := NewTextureView(0, 0, TEXTURE_FORMAT_4b, 0, 0)
+	showTexture := false
+	showVRAMOverlay := false
+
+	var event sdl.Event
+	var running bool = true
+
+	for running {
+		for event = sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			switch t := event.(type) {
+			case *sdl.QuitEvent:
+				running = false
+			case *sdl.KeyboardEvent:
+				keyCode := t.Keysym.Sym
+				if keyCode == 113 {
+					// use q to toggle cpu logging
+					gopsx.log = !gopsx.log
+				}
+				if keyCode == 116 && t.State == sdl.PRESSED {
+					// use t to toggle texture cache emulation
+					gopsx.GPU.SetTextureCacheEnabled(!gopsx.GPU.texCache.enabled)
+				}
+				if keyCode == 100 && t.State == sdl.PRESSED {
+					// use d to switch between vram view and display output
+					showDisplay = !showDisplay
+				}
+				if keyCode == 105 && t.State == sdl.PRESSED {
+					// use i to switch between weave and bob deinterlacing
+					display.ToggleDeinterlaceMode()
+				}
+				if keyCode == 112 && t.State == sdl.PRESSED {
+					// use p to toggle the primitive inspector
+					gopsx.GPU.primitiveLog.SetEnabled(!gopsx.GPU.primitiveLog.Enabled())
+					inspected = nil
+					inspectX, inspectY = -1, -1
+				}
+				if keyCode == 118 && t.State == sdl.PRESSED {
+					// use v to switch between the texture viewer and the other views
+					showTexture = !showTexture
+				}
+				if keyCode == 102 && t.State == sdl.PRESSED {
+					// use f to cycle the texture format of the texture viewer (4bit, 8bit, 15bit)
+					textureView.Format = (textureView.Format + 1) % TEXTURE_FORMAT_reserved
+				}
+				if keyCode == 111 && t.State == sdl.PRESSED {
+					// use o to outline display/drawing area and the texture page/clut of the texture viewer on the vram view
+					showVRAMOverlay = !showVRAMOverlay
+				}
+				if t.State == sdl.PRESSED {
+					// use the arrow keys to pick the texture page of the texture viewer
+					switch keyCode {
+					case sdl.K_LEFT:
+						textureView.PageX = Modulo(textureView.PageX-64, VRAM_WIDTH)
+					case sdl.K_RIGHT:
+						textureView.PageX = Modulo(textureView.PageX+64, VRAM_WIDTH)
+					case sdl.K_UP, sdl.K_DOWN:
+						textureView.PageY ^= 256
+					}
+				}
+				if keyCode == 115 && t.State == sdl.PRESSED {
+					// use s to save a screenshot of the display output (or of the whole vram in the vram view)
+					path := CaptureName(".", "png")
+					if err := gopsx.Screenshot(display, path, !showDisplay); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to save screenshot: %s\n", err)
+					} else {
+						fmt.Printf("[main] saved %s\n", path)
+					}
+				}
+				if keyCode == 114 && t.State == sdl.PRESSED {
+					// use r to start/stop recording a video
+					if gopsx.Recording() {
+						gopsx.StopVideo()
+					} else if err := gopsx.StartVideo(CaptureName(".", "avi"), codec); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to record video: %s\n", err)
+					}
+				}
+				if keyCode == 117 && t.State == sdl.PRESSED {
+					// use u to list the unmapped memory accesses so far
+					gopsx.Bus.DumpUnhandledAccesses(os.Stdout)
+				}
+				if keyCode == 120 && t.State == sdl.PRESSED {
+					// use x to export the texture page of the texture viewer as png and tim
+					name := fmt.Sprintf("texpage_%d_%d_%s", textureView.PageX, textureView.PageY, []string{"4bit", "8bit", "15bit"}[textureView.Format])
+					if err := gopsx.GPU.ExportTexturePNG(textureView, name+".png"); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to export texture: %s\n", err)
+					}
+					if err := gopsx.GPU.ExportTextureTIM(textureView, name+".tim"); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to export texture: %s\n", err)
+					}
+				}
+			case *sdl.MouseButtonEvent:
+				if t.Type == sdl.MOUSEBUTTONDOWN && !showDisplay && !showTexture && t.Button == sdl.BUTTON_RIGHT {
+					// right click in the vram view picks the clut of the texture viewer
+					textureView.ClutX, textureView.ClutY = int(t.X)&^15, int(t.Y)
+				}
+				if t.Type == sdl.MOUSEBUTTONDOWN && !showDisplay && !showTexture && t.Button == sdl.BUTTON_MIDDLE {
+					// middle click in the vram view picks the texture page of the texture viewer
+					textureView.PageX, textureView.PageY = int(t.X)&^63, int(t.Y)&^255
+				}
+				if t.Type == sdl.MOUSEBUTTONDOWN && !showDisplay && !showTexture && t.Button == sdl.BUTTON_LEFT && gopsx.GPU.primitiveLog.Enabled() {
+					// click a pixel in the vram view to see which primitives drew it
+					inspectX, inspectY = int(t.X), int(t.Y)
+					inspected = gopsx.GPU.primitiveLog.At(inspectX, inspectY)
+					inspectedText = InspectorText(inspected, inspectX, inspectY)
+
+					// show the texture of the topmost textured primitive in the texture viewer
+					for _, record := range inspected {
+						if record.Textured && record.TexFormat != TEXTURE_FORMAT_reserved {
+							textureView = NewTextureView(record.TexPageUBase, record.TexPageVBase, record.TexFormat, record.ClutX, record.ClutY)
+						}
+					}
+				}
+			}
+		}
+
+		gopsx.Update()
+
+		if showTexture {
+			img := gopsx.GPU.DecodeTexture(textureView)
+			rect := sdl.Rect{X: 0, Y: 0, W: int32(img.Bounds().Dx()), H: int32(img.Bounds().Dy())}
+
+			// texels are shown 2x2 so a whole page fills the window vertically
+			renderer.SetDrawColor(0, 0, 0, 0xff)
+			renderer.Clear()
+			displayTexture.Update(&rect, unsafe.Pointer(&img.Pix[0]), img.Stride)
+			renderer.Copy(displayTexture, &rect, &sdl.Rect{X: 0, Y: 0, W: rect.W * 2, H: rect.H * 2})
+		} else if showDisplay {
+			img := display.Render(gopsx.GPU)
+			rect := sdl.Rect{X: 0, Y: 0, W: int32(img.Bounds().Dx()), H: int32(img.Bounds().Dy())}
+
+			displayTexture.Update(&rect, unsafe.Pointer(&img.Pix[0]), img.Stride)
+			renderer.Copy(displayTexture, &rect, nil)
+		} else {
+			texture.Update(nil, unsafe.Pointer(&gopsx.GPU.vram.buffer[0]), VRAM_WIDTH*2)
+			renderer.Copy(texture, nil, nil)
+
+			if showVRAMOverlay {
+				drawVRAMOverlay(renderer, gopsx.GPU, textureView)
+			}
+			if gopsx.GPU.primitiveLog.Enabled() && inspectX >= 0 {
+				drawPrimitiveOverlay(renderer, inspectorTexture, inspected, inspectedText, inspectX, inspectY)
+			}
+		}
+
+		renderer.Present()
+	}
+
+	return 0
+}
+
+/*
+Outlines the display area (green), the drawing area (blue) and the texture page (magenta) and clut (cyan)
+shown by the texture viewer on top of the vram view
+*/
+func drawVRAMOverlay(renderer *sdl.Renderer, gpu *GPU, view *TextureView) {
+	outline := func(x, y, width, height int) {
+		renderer.DrawRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(width), H: int32(height)})
+	}
+
+	renderer.SetDrawColor(0, 0xff, 0, 0xff)
+	outline(gpu.DisplayArea())
+
+	renderer.SetDrawColor(0, 0x80, 0xff, 0xff)
+	outline(gpu.DrawingArea())
+
+	renderer.SetDrawColor(0xff, 0, 0xff, 0xff)
+	outline(view.VRAMArea())
+
+	if view.ClutSize() > 0 {
+		renderer.SetDrawColor(0, 0xff, 0xff, 0xff)
+		outline(view.ClutX, view.ClutY, view.ClutSize(), 1)
+	}
+}
+
+/*
+Outlines the primitives covering the inspected pixel on top of the vram view; the last one drawn (ie. the
+one whose pixel is visible, unless it was transparent) in red, the others in yellow. Their description
+(see InspectorText) is shown on the half of the view which doesn't have the pixel.
+*/
+func drawPrimitiveOverlay(renderer *sdl.Renderer, texture *sdl.Texture, records []*PrimitiveRecord, text *image.RGBA, x, y int) {
+	for i, record := range records {
+		if i == len(records)-1 {
+			renderer.SetDrawColor(0xff, 0, 0, 0xff)
+		} else {
+			renderer.SetDrawColor(0xff, 0xff, 0, 0xff)
+		}
+
+		v := record.Vertices
+		if record.Shape == PRIMITIVE_RECTANGLE {
+			renderer.DrawRect(&sdl.Rect{X: int32(v[0].x), Y: int32(v[0].y), W: int32(v[1].x - v[0].x), H: int32(v[1].y - v[0].y)})
+			continue
+		}
+
+		// 1--2
+		// |  |
+		// 3--4
+		outline := []int{0, 1, 2, 0}
+		if len(v) == 4 {
+			outline = []int{0, 1, 3, 2, 0}
+		}
+
+		points := make([]sdl.Point, len(outline))
+		for j, n := range outline {
+			points[j] = sdl.Point{X: int32(v[n].x), Y: int32(v[n].y)}
+		}
+		renderer.DrawLines(points)
+	}
+
+	renderer.SetDrawColor(0, 0xff, 0xff, 0xff)
+	renderer.DrawRect(&sdl.Rect{X: int32(x - 2), Y: int32(y - 2), W: 5, H: 5})
+
+	rect := sdl.Rect{X: 0, Y: 0, W: int32(text.Bounds().Dx()), H: int32(text.Bounds().Dy())}
+	target := rect
+	if x < VRAM_WIDTH/2 {
+		target.X = VRAM_WIDTH - rect.W
+	}
+
+	texture.Update(&rect, unsafe.Pointer(&text.Pix[0]), text.Stride)
+	renderer.Copy(texture, &rect, &target)
+}
+
+func main() {
+	os.Exit(run())
+}
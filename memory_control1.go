@@ -1,125 +1,233 @@
-package main
-
-import (
-	"fmt"
-)
-
-const (
-	MC1_OFFSET = 0x1f801000
-	MC1_SIZE   = 0x24
-)
-
-/*
-https://psx-spx.consoledev.net/iomap/#memory-control-1
-
-	1F801000h 4    Expansion 1 Base Address (usually 1F000000h)
-	1F801004h 4    Expansion 2 Base Address (usually 1F802000h)
-	1F801008h 4    Expansion 1 Delay/Size (usually 0013243Fh; 512Kbytes 8bit-bus)
-	1F80100Ch 4    Expansion 3 Delay/Size (usually 00003022h; 1 byte)
-	1F801010h 4    BIOS ROM    Delay/Size (usually 0013243Fh; 512Kbytes 8bit-bus)
-	1F801014h 4    SPU_DELAY   Delay/Size (usually 200931E1h)
-	1F801018h 4    CDROM_DELAY Delay/Size (usually 00020843h or 00020943h)
-	1F80101Ch 4    Expansion 2 Delay/Size (usually 00070777h; 128-bytes 8bit-bus)
-	1F801020h 4    COM_DELAY / COMMON_DELAY (00031125h or 0000132Ch or 00001325h)
-*/
-type MemoryControl1 struct {
-	exp1_base_addr uint32
-	exp2_base_addr uint32
-	exp1_delay     uint32
-	exp3_delay     uint32
-	bios_delay     uint32
-	spu_delay      uint32
-	cdrom_delay    uint32
-	exp2_delay     uint32
-	common_delay   uint32
-}
-
-func NewMemoryControl1() *MemoryControl1 {
-	return &MemoryControl1{
-		0x1f000000,
-		0x1f802000,
-		0x0013243f,
-		0x00003022,
-		0x0013243f,
-		0x200931e1,
-		0x00020843,
-		0x00070777,
-		0x00031125,
-	}
-}
-
-func (mc1 *MemoryControl1) Contains(address uint32) bool {
-	return address >= MC1_OFFSET && address < (MC1_OFFSET+MC1_SIZE)
-}
-
-func (mc1 *MemoryControl1) Read32(address uint32) uint32 {
-	switch address {
-	case 0x1f801000:
-		return mc1.exp1_base_addr
-	case 0x1f801004:
-		return mc1.exp2_base_addr
-	case 0x1f801008:
-		return mc1.exp1_delay
-	case 0x1f80100c:
-		return mc1.exp3_delay
-	case 0x1f801010:
-		return mc1.bios_delay
-	case 0x1f801014:
-		return mc1.spu_delay
-	case 0x1f801018:
-		return mc1.cdrom_delay
-	case 0x1f80101c:
-		return mc1.exp2_delay
-	case 0x1f801020:
-		return mc1.common_delay
-	default:
-		panic(fmt.Sprintf("[MemoryControl1::Read32] Unknown address: %x", address))
-	}
-}
-
-func (mc1 *MemoryControl1) Write32(address uint32, data uint32) {
-	switch address {
-	case 0x1f801000:
-		if mc1.exp1_base_addr != data {
-			/* cuz the base addresses are fixed */
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 1 base address: %x", data))
-		}
-	case 0x1f801004:
-		if mc1.exp2_base_addr != data {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 2 base address: %x", data))
-		}
-	case 0x1f801008:
-		if mc1.exp1_delay != data {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 1 delay value: %x", data))
-		}
-	case 0x1f80100c:
-		if mc1.exp3_delay != data {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 3 delay value: %x", data))
-		}
-	case 0x1f801010:
-		if mc1.bios_delay != data {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad BIOS delay value: %x", data))
-		}
-	case 0x1f801014:
-		if mc1.spu_delay != data {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad SPU delay value: %x", data))
-		}
-	case 0x1f801018:
-		if data != 0x00020843 && data != 0x00020943 {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad CDROM delay value: %x", data))
-		}
-		mc1.cdrom_delay = data
-	case 0x1f80101c:
-		if mc1.exp2_delay != data {
-			// panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 2 delay value: %x", data))
-		}
-		mc1.exp2_delay = data
-	case 0x1f801020:
-		if data != 0x00031125 && data != 0x0000132c && data != 0x00001325 {
-			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad common delay value: %x", data))
-		}
-		mc1.common_delay = data
-	default:
-		panic(fmt.Sprintf("[MemoryControl1::Write32] Unknown address: %x", address))
-	}
-}
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	MC1_OFFSET = 0x1f801000
+	MC1_SIZE   = 0x24
+)
+
+/*
+https://psx-spx.consoledev.net/iomap/#memory-control-1
+
+	1F801000h 4    Expansion 1 Base Address (usually 1F000000h)
+	1F801004h 4    Expansion 2 Base Address (usually 1F802000h)
+	1F801008h 4    Expansion 1 Delay/Size (usually 0013243Fh; 512Kbytes 8bit-bus)
+	1F80100Ch 4    Expansion 3 Delay/Size (usually 00003022h; 1 byte)
+	1F801010h 4    BIOS ROM    Delay/Size (usually 0013243Fh; 512Kbytes 8bit-bus)
+	1F801014h 4    SPU_DELAY   Delay/Size (usually 200931E1h)
+	1F801018h 4    CDROM_DELAY Delay/Size (usually 00020843h or 00020943h)
+	1F80101Ch 4    Expansion 2 Delay/Size (usually 00070777h; 128-bytes 8bit-bus)
+	1F801020h 4    COM_DELAY / COMMON_DELAY (00031125h or 0000132Ch or 00001325h)
+
+Delay/Size registers:
+
+	0-3   Write Delay        (00h..0Fh=01h..10h Cycles)
+	4-7   Read Delay         (00h..0Fh=01h..10h Cycles)
+	8     Recovery Period    (0=No, 1=Yes, uses COM0 timings)
+	9     Hold Period        (0=No, 1=Yes, uses COM1 timings)
+	10    Floating Period    (0=No, 1=Yes, uses COM2 timings)
+	11    Pre-strobe Period  (0=No, 1=Yes, uses COM3 timings)
+	12    Data Bus-width     (0=8bits, 1=16bits)
+	13    Auto Increment     (0=No, 1=Yes)
+	14-15 Unknown (R/W)
+	16-20 Number of address bits (memory window size = 1 SHL N bytes)
+
+COM_DELAY:
+
+	0-3   COM0 - Recovery period cycles
+	4-7   COM1 - Hold period cycles
+	8-11  COM2 - Floating release cycles
+	12-15 COM3 - Strobe active-going edge delay
+*/
+const (
+	MC1_REGION_EXP1 = iota
+	MC1_REGION_EXP3
+	MC1_REGION_BIOS
+	MC1_REGION_SPU
+	MC1_REGION_CDROM
+	MC1_REGION_EXP2
+	MC1_REGIONS
+)
+
+/* width of a bus access */
+const (
+	ACCESS_BYTE = iota
+	ACCESS_HALFWORD
+	ACCESS_WORD
+)
+
+type MemoryControl1 struct {
+	exp1_base_addr uint32
+	exp2_base_addr uint32
+	exp1_delay     uint32
+	exp3_delay     uint32
+	bios_delay     uint32
+	spu_delay      uint32
+	cdrom_delay    uint32
+	exp2_delay     uint32
+	common_delay   uint32
+
+	/* cycles an access takes on top of the cycle of the instruction; [region][write][width] */
+	accessTimes [MC1_REGIONS][2][3]uint32
+}
+
+func NewMemoryControl1() *MemoryControl1 {
+	mc1 := &MemoryControl1{
+		0x1f000000,
+		0x1f802000,
+		0x0013243f,
+		0x00003022,
+		0x0013243f,
+		0x200931e1,
+		0x00020843,
+		0x00070777,
+		0x00031125,
+		[MC1_REGIONS][2][3]uint32{},
+	}
+
+	mc1.UpdateAccessTimes()
+
+	return mc1
+}
+
+func (mc1 *MemoryControl1) Contains(address uint32) bool {
+	return address >= MC1_OFFSET && address < (MC1_OFFSET+MC1_SIZE)
+}
+
+func (mc1 *MemoryControl1) Read32(address uint32) uint32 {
+	switch address {
+	case 0x1f801000:
+		return mc1.exp1_base_addr
+	case 0x1f801004:
+		return mc1.exp2_base_addr
+	case 0x1f801008:
+		return mc1.exp1_delay
+	case 0x1f80100c:
+		return mc1.exp3_delay
+	case 0x1f801010:
+		return mc1.bios_delay
+	case 0x1f801014:
+		return mc1.spu_delay
+	case 0x1f801018:
+		return mc1.cdrom_delay
+	case 0x1f80101c:
+		return mc1.exp2_delay
+	case 0x1f801020:
+		return mc1.common_delay
+	default:
+		panic(fmt.Sprintf("[MemoryControl1::Read32] Unknown address: %x", address))
+	}
+}
+
+func (mc1 *MemoryControl1) Write32(address uint32, data uint32) {
+	switch address {
+	case 0x1f801000:
+		if mc1.exp1_base_addr != data {
+			/* cuz the base addresses are fixed */
+			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 1 base address: %x", data))
+		}
+	case 0x1f801004:
+		if mc1.exp2_base_addr != data {
+			panic(fmt.Sprintf("[MemoryControl1::Write32] Bad expansion 2 base address: %x", data))
+		}
+	case 0x1f801008:
+		mc1.exp1_delay = data
+	case 0x1f80100c:
+		mc1.exp3_delay = data
+	case 0x1f801010:
+		mc1.bios_delay = data
+	case 0x1f801014:
+		mc1.spu_delay = data
+	case 0x1f801018:
+		mc1.cdrom_delay = data
+	case 0x1f80101c:
+		mc1.exp2_delay = data
+	case 0x1f801020:
+		mc1.common_delay = data
+	default:
+		panic(fmt.Sprintf("[MemoryControl1::Write32] Unknown address: %x", address))
+	}
+
+	mc1.UpdateAccessTimes()
+}
+
+func (mc1 *MemoryControl1) AccessTime(region int, width int, write bool) uint32 {
+	if write {
+		return mc1.accessTimes[region][1][width]
+	}
+
+	return mc1.accessTimes[region][0][width]
+}
+
+func (mc1 *MemoryControl1) UpdateAccessTimes() {
+	delays := []uint32{
+		mc1.exp1_delay,
+		mc1.exp3_delay,
+		mc1.bios_delay,
+		mc1.spu_delay,
+		mc1.cdrom_delay,
+		mc1.exp2_delay,
+	}
+
+	for region, delay := range delays {
+		mc1.accessTimes[region][0] = AccessTimes(GetRange(delay, 4, 4), delay, mc1.common_delay)
+		mc1.accessTimes[region][1] = AccessTimes(GetRange(delay, 0, 4), delay, mc1.common_delay)
+	}
+}
+
+/*
+Cycles for a byte, halfword and word access to a region (minus the cycle of the instruction itself)
+
+Accesses wider than the data bus are split up; the first access pays for the recovery/floating periods and
+the strobe delay, the following ones only for the recovery/floating periods (same as the nocash specs
+and the access-time test of ps1-tests)
+*/
+func AccessTimes(cycles uint32, delay uint32, common uint32) [3]uint32 {
+	var first, seq, min int
+
+	if TestBit(delay, 8) {
+		first += int(GetRange(common, 0, 4)) - 1
+		seq += int(GetRange(common, 0, 4)) - 1
+	}
+
+	if TestBit(delay, 10) {
+		first += int(GetRange(common, 8, 4))
+		seq += int(GetRange(common, 8, 4))
+	}
+
+	if TestBit(delay, 11) {
+		min = int(GetRange(common, 12, 4))
+	}
+
+	if first < 6 {
+		first += 1
+	}
+
+	first += int(cycles) + 2
+	seq += int(cycles) + 2
+
+	first = MaxOf(first, min+6)
+	seq = MaxOf(seq, min+2)
+
+	var times [3]int
+	if TestBit(delay, 12) {
+		// 16bit bus
+		times[ACCESS_BYTE] = first
+		times[ACCESS_HALFWORD] = first
+		times[ACCESS_WORD] = first + seq
+	} else {
+		times[ACCESS_BYTE] = first
+		times[ACCESS_HALFWORD] = first + seq
+		times[ACCESS_WORD] = first + seq*3
+	}
+
+	var cpuTimes [3]uint32
+	for i, time := range times {
+		cpuTimes[i] = uint32(MaxOf(time-1, 0))
+	}
+
+	return cpuTimes
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+/*
+Delays worked out by hand from the Delay/Size and COM_DELAY registers (see AccessTimes); the bios
+values are the ones the kernel sets up. These only check the formula: what a real console measures is in
+ps1-tests' cpu/access-time/psx.log, which TestCPUTestExecutables compares the access-time executable with.
+*/
+func TestAccessTimes(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    uint32
+		write    bool
+		expected [3]uint32
+	}{
+		// 8bit bus, 3 read cycles, floating period (COM2=1): first 7, then 6 per byte
+		{"bios read", 0x0013243f, false, [3]uint32{6, 12, 24}},
+		// 15 write cycles: first 19, then 18 per byte
+		{"bios write", 0x0013243f, true, [3]uint32{18, 36, 72}},
+		// 16bit bus, 14 read cycles, recovery period (COM0=5): first 21, then 20 per halfword
+		{"spu read", 0x200931e1, false, [3]uint32{20, 20, 40}},
+		{"spu write", 0x200931e1, true, [3]uint32{7, 7, 14}},
+		// 8bit bus, 4 read cycles, pre-strobe period (COM3=1) which doesn't add anything here
+		{"cdrom read", 0x00020843, false, [3]uint32{6, 12, 24}},
+		// the pre-strobe period makes the first access at least 7 cycles
+		{"cdrom write", 0x00020843, true, [3]uint32{6, 11, 21}},
+		// 8bit bus, 7 read cycles, recovery (COM0=5) and floating (COM2=1) periods: first 15, then 14 per byte
+		{"expansion 2 read", 0x00070777, false, [3]uint32{14, 28, 56}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cycles := GetRange(test.delay, 4, 4)
+			if test.write {
+				cycles = GetRange(test.delay, 0, 4)
+			}
+
+			if times := AccessTimes(cycles, test.delay, 0x00031125); times != test.expected {
+				t.Errorf("byte/halfword/word take %v cycles, expected %v", times, test.expected)
+			}
+		})
+	}
+}
+
+func TestBusAccessCycles(t *testing.T) {
+	bus := NewTestGoStation(t).Bus
+
+	tests := []struct {
+		name     string
+		address  uint32
+		width    int
+		write    bool
+		expected uint32
+	}{
+		{"ram read", 0x00001000, ACCESS_WORD, false, RAM_READ_CYCLES},
+		{"ram mirror read", 0x00601000, ACCESS_BYTE, false, RAM_READ_CYCLES},
+		{"ram write", 0x00001000, ACCESS_WORD, true, 0},
+		{"i/o read", 0x1f801070, ACCESS_WORD, false, IO_READ_CYCLES},
+		{"i/o write", 0x1f801070, ACCESS_WORD, true, 0},
+		{"bios byte read", 0x1fc00000, ACCESS_BYTE, false, 6},
+		{"bios word read", 0x1fc00000, ACCESS_WORD, false, 24},
+		{"cdrom read", 0x1f801800, ACCESS_BYTE, false, 6},
+		{"spu read", 0x1f801c00, ACCESS_HALFWORD, false, 20},
+		{"expansion 1 read", 0x1f000000, ACCESS_WORD, false, 24},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cycles := bus.AccessCycles(test.address, test.width, test.write); cycles != test.expected {
+				t.Errorf("%d cycles, expected %d", cycles, test.expected)
+			}
+		})
+	}
+
+	// the bios region on a 16bit bus with no delays: the first halfword takes the minimum of 6, the second 2
+	bus.MemoryControl1.Write32(0x1f801010, 0x00131000)
+	if cycles := bus.AccessCycles(0x1fc00000, ACCESS_WORD, false); cycles != 7 {
+		t.Errorf("bios word read takes %d cycles after changing its delay, expected 7", cycles)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+/*
+Precision geometry (PGXP-style)
+
+The gte computes screen coordinates with more precision than the 16.16 integers games write to GP0, so
+polygons wobble (vertexes snap to whole pixels) and textures warp (the gpu interpolates texture
+coordinates affinely). With precision geometry enabled:
+
+ 1. the gte records the precise position (and depth) of every vertex it outputs, keyed by the packed
+    SXY value which it writes to its screen fifo (PGXP::RecordScreenXY)
+ 2. cpu stores of such values remember the precise vertex for the address they are written to
+    (PGXP::TrackStore); any other store to that address forgets it again
+ 3. words sent to GP0 via dma or by the cpu are matched with the precise vertex of the address
+    they come from (PGXP::Forward) or of their value (PGXP::Lookup)
+ 4. the gpu keeps the precise vertex of each argument of the command being received
+    (GPU::gp0Precise) and the rasterizer uses them for sub-pixel positions of every polygon and
+    perspective correct texture coordinates (see GPU::RasterizePrecise)
+
+It's off by default since the result is no longer what the real hardware draws.
+
+TODO the gte isn't implemented yet so nothing calls PGXP::RecordScreenXY; there is no -pgxp option until
+it is (and calls it from RTPS/RTPT) since turning it on wouldn't do anything
+*/
+type PreciseVertex struct {
+	x float64 /* screen coordinates (without drawing offset) */
+	y float64
+	w float64 /* depth; <= 0 if unknown */
+}
+
+/* caches are dropped when they get larger than this (eg. if a game never overwrites old vertexes) */
+const PGXP_MAX_ENTRIES = 1 << 16
+
+type PGXP struct {
+	enabled bool
+
+	outputs   map[uint32]*PreciseVertex /* gte screen coordinate outputs, keyed by packed 16.16 value */
+	memory    map[uint32]*PreciseVertex /* precise vertexes in ram/scratchpad, keyed by (word aligned) address */
+	forwarded map[uint32]*PreciseVertex /* precise vertexes of words on their way to GP0, keyed by value */
+}
+
+func NewPGXP() *PGXP {
+	return &PGXP{
+		false,
+		make(map[uint32]*PreciseVertex),
+		make(map[uint32]*PreciseVertex),
+		make(map[uint32]*PreciseVertex),
+	}
+}
+
+func (pgxp *PGXP) SetEnabled(enabled bool) {
+	pgxp.enabled = enabled
+
+	if !enabled {
+		pgxp.outputs = make(map[uint32]*PreciseVertex)
+		pgxp.memory = make(map[uint32]*PreciseVertex)
+		pgxp.forwarded = make(map[uint32]*PreciseVertex)
+	}
+}
+
+/* called by the gte whenever it pushes a screen coordinate (SXY2) */
+func (pgxp *PGXP) RecordScreenXY(sxy uint32, x, y, w float64) {
+	if !pgxp.enabled {
+		return
+	}
+
+	if len(pgxp.outputs) >= PGXP_MAX_ENTRIES {
+		pgxp.outputs = make(map[uint32]*PreciseVertex)
+	}
+
+	pgxp.outputs[sxy] = &PreciseVertex{x, y, w}
+}
+
+/* called for every 32 bit store to ram or the scratchpad */
+func (pgxp *PGXP) TrackStore(address uint32, data uint32) {
+	if !pgxp.enabled {
+		return
+	}
+
+	address &^= 3
+
+	if vertex, ok := pgxp.outputs[data]; ok {
+		if len(pgxp.memory) >= PGXP_MAX_ENTRIES {
+			pgxp.memory = make(map[uint32]*PreciseVertex)
+		}
+
+		pgxp.memory[address] = vertex
+	} else {
+		delete(pgxp.memory, address)
+	}
+}
+
+/* called for 8 and 16 bit stores; partial writes break the link between the word and its precise vertex */
+func (pgxp *PGXP) Invalidate(address uint32) {
+	if !pgxp.enabled {
+		return
+	}
+
+	delete(pgxp.memory, address&^3)
+}
+
+/* called by dma before a word read from address gets written to GP0 */
+func (pgxp *PGXP) Forward(address uint32, data uint32) {
+	if !pgxp.enabled {
+		return
+	}
+
+	if vertex, ok := pgxp.memory[address&^3]; ok {
+		if len(pgxp.forwarded) >= PGXP_MAX_ENTRIES {
+			pgxp.forwarded = make(map[uint32]*PreciseVertex)
+		}
+
+		pgxp.forwarded[data] = vertex
+	}
+}
+
+/* returns the precise vertex of a word written to GP0 or nil if there is none */
+func (pgxp *PGXP) Lookup(data uint32) *PreciseVertex {
+	if !pgxp.enabled {
+		return nil
+	}
+
+	if vertex, ok := pgxp.forwarded[data]; ok {
+		return vertex
+	}
+
+	// written by the cpu straight from a gte register
+	return pgxp.outputs[data]
+}
+
+/*
+Attaches the precise position of GP0 argument i (if any) to a vertex decoded from it. Precise positions
+which are more than a pixel away from the integer one belong to some other vertex with the same packed value.
+*/
+func (gpu *GPU) RefineVertex(v *Vertex, i int) {
+	precise := gpu.gp0Precise[i]
+	if precise == nil {
+		return
+	}
+
+	x := precise.x + float64(gpu.drawingXOffset)
+	y := precise.y + float64(gpu.drawingYOffset)
+
+	if x-float64(v.x) <= -1 || x-float64(v.x) >= 1 || y-float64(v.y) <= -1 || y-float64(v.y) >= 1 {
+		return
+	}
+
+	v.precise = &PreciseVertex{x, y, precise.w}
+}
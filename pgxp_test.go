@@ -0,0 +1,250 @@
+package main
+
+import (
+	"testing"
+)
+
+/* reference renderer which remembers the vertexes of the triangles it draws */
+type VertexRecorder struct {
+	SoftwareRenderer
+	triangles [][3]*Vertex
+}
+
+func (renderer *VertexRecorder) DrawTriangle(gpu *GPU, v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	renderer.triangles = append(renderer.triangles, [3]*Vertex{v1, v2, v3})
+	renderer.SoftwareRenderer.DrawTriangle(gpu, v1, v2, v3, stMode, attr)
+}
+
+func (renderer *VertexRecorder) DrawTexturedTriangle(gpu *GPU, v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	renderer.triangles = append(renderer.triangles, [3]*Vertex{v1, v2, v3})
+	renderer.SoftwareRenderer.DrawTexturedTriangle(gpu, v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, attr)
+}
+
+/* textured quad (0,0)-(100,100) drawn with a drawing offset of (10,20); returns the packed vertexes */
+func PGXPTestQuad() ([]uint32, []uint32) {
+	texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+	sxy := []uint32{VertexWord(0, 0), VertexWord(100, 3), VertexWord(2, 100), VertexWord(101, 101)}
+
+	return sxy, []uint32{
+		0xe5000000 | 20<<11 | 10,
+		0x2c808080,
+		sxy[0], 0x0000,
+		sxy[1], texPage<<16 | 31,
+		sxy[2], 31 << 8,
+		sxy[3], 31<<8 | 31,
+	}
+}
+
+/* sub-pixel positions recorded for the quad; depths grow to the right */
+var pgxpTestPositions = [][3]float64{{0.25, 0.5, 100}, {100.5, 2.75, 300}, {1.75, 100.25, 100}, {100.5, 100.5, 300}}
+
+func NewPGXPTestGPU(enabled bool, recorder *VertexRecorder) *GPU {
+	gpu := NewTestGPU()
+	UploadTestTexture(gpu)
+	gpu.SetRenderer(recorder)
+	gpu.Core.PGXP.SetEnabled(enabled)
+
+	return gpu
+}
+
+func TestPreciseVertexesReachTheRasterizer(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(gpu *GPU, sxy []uint32, words []uint32)
+	}{
+		{
+			// the cpu writes the gte's screen coordinates to GP0 itself
+			"cpu",
+			func(gpu *GPU, sxy []uint32, words []uint32) {
+				SendGP0(gpu, words...)
+			},
+		},
+		{
+			// the coordinates are stored to ram and sent to GP0 by dma; the gte output cache doesn't know them
+			// anymore by then
+			"dma",
+			func(gpu *GPU, sxy []uint32, words []uint32) {
+				pgxp := gpu.Core.PGXP
+				for i, word := range sxy {
+					pgxp.TrackStore(0x1000+uint32(i)*4, word)
+				}
+				for i, word := range sxy {
+					pgxp.Forward(0x1000+uint32(i)*4, word)
+				}
+				pgxp.outputs = make(map[uint32]*PreciseVertex)
+
+				SendGP0(gpu, words...)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &VertexRecorder{}
+			gpu := NewPGXPTestGPU(true, recorder)
+
+			sxy, words := PGXPTestQuad()
+			for i, position := range pgxpTestPositions {
+				gpu.Core.PGXP.RecordScreenXY(sxy[i], position[0], position[1], position[2])
+			}
+
+			test.send(gpu, sxy, words)
+
+			if len(recorder.triangles) != 2 {
+				t.Fatalf("%d triangles drawn, expected 2", len(recorder.triangles))
+			}
+
+			// v1, v2, v3 and v2, v3, v4
+			for i, triangle := range recorder.triangles {
+				for j, v := range triangle {
+					position := pgxpTestPositions[i+j]
+
+					if v.precise == nil {
+						t.Fatalf("vertex %d of triangle %d has no precise position", j, i)
+					}
+					if v.precise.x != position[0]+10 || v.precise.y != position[1]+20 || v.precise.w != position[2] {
+						t.Errorf("vertex %d of triangle %d is %+v, expected (%v,%v) with the drawing offset", j, i, *v.precise, position[0], position[1])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPreciseVertexesOfOtherValuesAreIgnored(t *testing.T) {
+	recorder := &VertexRecorder{}
+	gpu := NewPGXPTestGPU(true, recorder)
+
+	sxy, words := PGXPTestQuad()
+
+	// same packed value but far away (some other vertex), and a value that isn't sent at all
+	gpu.Core.PGXP.RecordScreenXY(sxy[0], 50, 50, 100)
+	gpu.Core.PGXP.RecordScreenXY(VertexWord(7, 7), 7.5, 7.5, 100)
+
+	SendGP0(gpu, words...)
+
+	for i, triangle := range recorder.triangles {
+		for j, v := range triangle {
+			if v.precise != nil {
+				t.Errorf("vertex %d of triangle %d has precise position %+v", j, i, *v.precise)
+			}
+		}
+	}
+}
+
+/*
+Recording precise vertexes with precision geometry off (and drawing with it on without any precise
+vertexes) is the same as never recording any
+*/
+func TestPGXPOffIsBitExact(t *testing.T) {
+	sxy, words := PGXPTestQuad()
+
+	reference := NewPGXPTestGPU(false, &VertexRecorder{})
+	SendGP0(reference, words...)
+
+	for _, enabled := range []bool{false, true} {
+		recorder := &VertexRecorder{}
+		gpu := NewPGXPTestGPU(enabled, recorder)
+
+		if !enabled {
+			for i, position := range pgxpTestPositions {
+				gpu.Core.PGXP.RecordScreenXY(sxy[i], position[0], position[1], position[2])
+				gpu.Core.PGXP.TrackStore(uint32(i)*4, sxy[i])
+				gpu.Core.PGXP.Forward(uint32(i)*4, sxy[i])
+			}
+		}
+
+		SendGP0(gpu, words...)
+
+		for _, triangle := range recorder.triangles {
+			for _, v := range triangle {
+				if v.precise != nil {
+					t.Fatalf("enabled %t: vertex has precise position %+v", enabled, *v.precise)
+				}
+			}
+		}
+
+		CompareVRAM(t, gpu, reference)
+	}
+
+	// and precise vertexes do change the picture
+	gpu := NewPGXPTestGPU(true, &VertexRecorder{})
+	for i, position := range pgxpTestPositions {
+		gpu.Core.PGXP.RecordScreenXY(sxy[i], position[0], position[1], position[2])
+	}
+	SendGP0(gpu, words...)
+
+	differ := 0
+	for i := range gpu.vram.buffer {
+		if gpu.vram.buffer[i] != reference.vram.buffer[i] {
+			differ += 1
+		}
+	}
+	if differ == 0 {
+		t.Error("drawing with precise vertexes is the same as without")
+	}
+}
+
+/* every kind of polygon (flat, shaded and both textured) gets the precise positions of its vertexes */
+func TestPreciseVertexesOfEveryPolygon(t *testing.T) {
+	sxy, _ := PGXPTestQuad()
+	texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+
+	tests := []struct {
+		name  string
+		words []uint32
+	}{
+		{"flat triangle", []uint32{0x20ff0000, sxy[0], sxy[1], sxy[2]}},
+		{"flat quad", []uint32{0x28ff0000, sxy[0], sxy[1], sxy[2], sxy[3]}},
+		{"shaded triangle", []uint32{0x30ff0000, sxy[0], 0x00ff00, sxy[1], 0x0000ff, sxy[2]}},
+		{"shaded quad", []uint32{0x38ff0000, sxy[0], 0x00ff00, sxy[1], 0x0000ff, sxy[2], 0xffffff, sxy[3]}},
+		{"textured triangle", []uint32{0x24808080, sxy[0], 0x0000, sxy[1], texPage<<16 | 31, sxy[2], 31 << 8}},
+		{"textured shaded quad", []uint32{
+			0x3c808080, sxy[0], 0x0000,
+			0x808080, sxy[1], texPage<<16 | 31,
+			0x808080, sxy[2], 31 << 8,
+			0x808080, sxy[3], 31<<8 | 31,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			draw := func(precise bool) (*GPU, *VertexRecorder) {
+				recorder := &VertexRecorder{}
+				gpu := NewPGXPTestGPU(true, recorder)
+
+				if precise {
+					for i, position := range pgxpTestPositions {
+						gpu.Core.PGXP.RecordScreenXY(sxy[i], position[0], position[1], position[2])
+					}
+				}
+				SendGP0(gpu, test.words...)
+
+				return gpu, recorder
+			}
+
+			gpu, recorder := draw(true)
+			if len(recorder.triangles) == 0 {
+				t.Fatal("nothing drawn")
+			}
+			for i, triangle := range recorder.triangles {
+				for j, v := range triangle {
+					if v.precise == nil {
+						t.Errorf("vertex %d of triangle %d has no precise position", j, i)
+					}
+				}
+			}
+
+			reference, _ := draw(false)
+			differ := 0
+			for i := range gpu.vram.buffer {
+				if gpu.vram.buffer[i] != reference.vram.buffer[i] {
+					differ += 1
+				}
+			}
+			if differ == 0 {
+				t.Error("drawing with precise vertexes is the same as without")
+			}
+		})
+	}
+}
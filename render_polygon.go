@@ -1,333 +1,468 @@
-package main
-
-const (
-	PATTR_RAW_TEXTURE = iota
-	PATTR_SEMI_TRANSPARENT
-	PATTR_TEXTURE
-	PATTR_QUAD
-	PATTR_GOURAUD
-)
-
-func (gpu *GPU) ProcessMonochromeQuadCommand() {
-	colour := gpu.fifo.buffer[0]
-
-	v1 := NewVertex(gpu.fifo.buffer[1], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[2], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[3], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v4 := NewVertex(gpu.fifo.buffer[4], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-
-	gpu.RenderTriangle(v1, v2, v3, gpu.semiTransparency, gpu.shape_attr, false)
-	gpu.RenderTriangle(v2, v3, v4, gpu.semiTransparency, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessShadedQuadCommand() {
-	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[3], gpu.fifo.buffer[2], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[5], gpu.fifo.buffer[4], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v4 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-
-	gpu.RenderTriangle(v1, v2, v3, gpu.semiTransparency, gpu.shape_attr, false)
-	gpu.RenderTriangle(v2, v3, v4, gpu.semiTransparency, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessTexturedQuadCommand() {
-	colour := gpu.fifo.buffer[0]
-
-	v1 := NewVertex(gpu.fifo.buffer[1], colour, gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[3], colour, gpu.fifo.buffer[4], gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[5], colour, gpu.fifo.buffer[6], gpu.drawingXOffset, gpu.drawingYOffset)
-	v4 := NewVertex(gpu.fifo.buffer[7], colour, gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
-
-	clutIndex := gpu.fifo.buffer[2] >> 16
-	texPage := gpu.fifo.buffer[4] >> 16
-
-	clutX := int(GetRange(clutIndex, 0, 6) * 16)
-	clutY := int(GetRange(clutIndex, 6, 9))
-
-	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
-	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
-
-	stMode := int(GetRange(texPage, 5, 2))
-
-	texFormat := int(GetRange(texPage, 7, 2))
-
-	if texFormat == TEXTURE_FORMAT_reserved {
-		panic("[GPU::ProcessTexturedQuadCommand] reserved texture format")
-	}
-
-	gpu.RenderTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, false)
-	gpu.RenderTexturedTriangle(v2, v3, v4, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessTexturedShadedQuadCommand() {
-	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[4], gpu.fifo.buffer[3], gpu.fifo.buffer[5], gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
-	v4 := NewVertex(gpu.fifo.buffer[10], gpu.fifo.buffer[9], gpu.fifo.buffer[11], gpu.drawingXOffset, gpu.drawingYOffset)
-
-	clutIndex := gpu.fifo.buffer[2] >> 16
-	texPage := gpu.fifo.buffer[5] >> 16
-
-	clutX := int(GetRange(clutIndex, 0, 6) * 16)
-	clutY := int(GetRange(clutIndex, 6, 9))
-
-	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
-	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
-
-	stMode := int(GetRange(texPage, 5, 2))
-
-	texFormat := int(GetRange(texPage, 7, 2))
-
-	if texFormat == TEXTURE_FORMAT_reserved {
-		panic("[GPU::ProcessTexturedShadedQuadCommand] reserved texture format")
-	}
-
-	gpu.RenderTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, false)
-	gpu.RenderTexturedTriangle(v2, v3, v4, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessMonochromeTrigCommand() {
-	colour := gpu.fifo.buffer[0]
-
-	v1 := NewVertex(gpu.fifo.buffer[1], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[2], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[3], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
-
-	gpu.RenderTriangle(v1, v2, v3, gpu.semiTransparency, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessShadedTrigCommand() {
-	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[3], gpu.fifo.buffer[2], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[5], gpu.fifo.buffer[4], 0, gpu.drawingXOffset, gpu.drawingYOffset)
-
-	gpu.RenderTriangle(v1, v2, v3, gpu.semiTransparency, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessTexturedTrigCommand() {
-	colour := gpu.fifo.buffer[0]
-
-	v1 := NewVertex(gpu.fifo.buffer[1], colour, gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[3], colour, gpu.fifo.buffer[4], gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[5], colour, gpu.fifo.buffer[6], gpu.drawingXOffset, gpu.drawingYOffset)
-
-	clutIndex := gpu.fifo.buffer[2] >> 16
-	texPage := gpu.fifo.buffer[4] >> 16
-
-	clutX := int(GetRange(clutIndex, 0, 6) * 16)
-	clutY := int(GetRange(clutIndex, 6, 9))
-
-	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
-	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
-
-	stMode := int(GetRange(texPage, 5, 2))
-
-	texFormat := int(GetRange(texPage, 7, 2))
-
-	if texFormat == TEXTURE_FORMAT_reserved {
-		panic("[GPU::ProcessTexturedTrigCommand] reserved texture format")
-	}
-
-	gpu.RenderTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, true)
-}
-
-func (gpu *GPU) ProcessTexturedShadedTrigCommand() {
-	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
-	v2 := NewVertex(gpu.fifo.buffer[4], gpu.fifo.buffer[3], gpu.fifo.buffer[5], gpu.drawingXOffset, gpu.drawingYOffset)
-	v3 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
-
-	clutIndex := gpu.fifo.buffer[2] >> 16
-	texPage := gpu.fifo.buffer[5] >> 16
-
-	clutX := int(GetRange(clutIndex, 0, 6) * 16)
-	clutY := int(GetRange(clutIndex, 6, 9))
-
-	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
-	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
-
-	stMode := int(GetRange(texPage, 5, 2))
-
-	texFormat := int(GetRange(texPage, 7, 2))
-
-	if texFormat == TEXTURE_FORMAT_reserved {
-		panic("[GPU::ProcessTexturedShadedTrigCommand] reserved texture format")
-	}
-
-	gpu.RenderTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr, true)
-}
-
-/*
-Note: v1, v2, and v3 must be in clockwise order
-*/
-func (gpu *GPU) RenderTexturedTriangle(v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32, ccw bool) {
-	area := Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y)
-	if ccw && area < 0 {
-		// negative area! let's swap vertexes (but sometimes accept negative area...)
-		v2, v3 = v3, v2
-	}
-	area = Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y)
-
-	isRawTexture := TestBit(attr, PATTR_RAW_TEXTURE)
-	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
-
-	xmin := MinOf(v1.x, v2.x, v3.x)
-	xmax := MaxOf(v1.x, v2.x, v3.x)
-	ymin := MinOf(v1.y, v2.y, v3.y)
-	ymax := MaxOf(v1.y, v2.y, v3.y)
-
-	// TODO clipping
-
-	topLeft12 := IsTopLeft(v1, v2) // v1-v2 edge
-	topLeft23 := IsTopLeft(v2, v3) // v2-v3 edge
-	topLeft31 := IsTopLeft(v3, v1) // v3-v1 edge
-
-	w1Row := Edge(v3.x, v3.y, v2.x, v2.y, xmin, ymin) // 2-3
-	w2Row := Edge(v1.x, v1.y, v3.x, v3.y, xmin, ymin) // 3-1
-	w3Row := Edge(v2.x, v2.y, v1.x, v1.y, xmin, ymin) // 1-2
-
-	incX23 := v2.y - v3.y
-	incX31 := v3.y - v1.y
-	incX12 := v1.y - v2.y
-	incY23 := v3.x - v2.x
-	incY31 := v1.x - v3.x
-	incY12 := v2.x - v1.x
-
-	for y := ymin; y <= ymax; y += 1 {
-		w1 := w1Row
-		w2 := w2Row
-		w3 := w3Row
-
-		for x := xmin; x <= xmax; x += 1 {
-			if gpu.drawUnmaskedPixels {
-				pix := uint32(gpu.vram.Read16(x, y))
-
-				if TestBit(pix, 15) {
-					// masked
-					w1 += incX23
-					w2 += incX31
-					w3 += incX12
-					continue
-				}
-			}
-
-			if (w1 > 0 || (w1 == 0 && topLeft23)) &&
-				(w2 > 0 || (w2 == 0 && topLeft31)) &&
-				(w3 > 0 || (w3 == 0 && topLeft12)) {
-				u := (w1*v1.u + w2*v2.u + w3*v3.u) / area
-				v := (w1*v1.v + w2*v2.v + w3*v3.v) / area
-
-				texel := gpu.GetTexel(u, v, clutX, clutY, texPageUBase, texPageVBase, texFormat)
-
-				if texel > 0 {
-					tr := int(GetRange(texel, 0, 5) << 3)
-					tg := int(GetRange(texel, 5, 5) << 3)
-					tb := int(GetRange(texel, 10, 5) << 3)
-					stp := TestBit(texel, 15)
-
-					// TODO texture masking?
-
-					if !isRawTexture {
-						tr, tg, tb = gpu.TextureBlend(
-							(w1*v1.r+w2*v2.r+w3*v3.r)/area,
-							(w1*v1.g+w2*v2.g+w3*v3.g)/area,
-							(w1*v1.b+w2*v2.b+w3*v3.b)/area,
-							tr,
-							tg,
-							tb,
-						)
-					}
-
-					/* Summary of semi-transparency handling: https://github.com/ABelliqueux/nolibgs_hello_worlds/wiki/TIM#transparency
-
-					semi-transparency mode is set thru bit 25 of command
-					16-bit texel (format: STP, B, G, R) | semi-transparency mode off  | semi-transparency mode on
-					(0, 0, 0, 0)                          transparent (no draw)         transparent (no draw)
-					(1, 0, 0, 0)                          non-transparent (draw black)  semi-transparent
-					(0, n, n, n)                          non-transparent               non-transparent
-					(1, n, n, n)                          non-transparent               semi-transparent
-					*/
-					gpu.PutPixel(x, y, tr, tg, tb, stp, isSemiTransparent && stp, stMode)
-				}
-			}
-
-			w1 += incX23
-			w2 += incX31
-			w3 += incX12
-		}
-
-		w1Row += incY23
-		w2Row += incY31
-		w3Row += incY12
-	}
-}
-
-func (gpu *GPU) RenderTriangle(v1, v2, v3 *Vertex, stMode int, attr uint32, ccw bool) {
-	area := Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y)
-	if ccw && area < 0 {
-		// negative area! let's swap vertexes (but sometimes accept negative area...)
-		v2, v3 = v3, v2
-	}
-	area = Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y)
-
-	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
-
-	xmin := MinOf(v1.x, v2.x, v3.x)
-	xmax := MaxOf(v1.x, v2.x, v3.x)
-	ymin := MinOf(v1.y, v2.y, v3.y)
-	ymax := MaxOf(v1.y, v2.y, v3.y)
-
-	// TODO clipping
-
-	topLeft12 := IsTopLeft(v1, v2) // v1-v2 edge
-	topLeft23 := IsTopLeft(v2, v3) // v2-v3 edge
-	topLeft31 := IsTopLeft(v3, v1) // v3-v1 edge
-
-	w1Row := Edge(v3.x, v3.y, v2.x, v2.y, xmin, ymin) // 2-3
-	w2Row := Edge(v1.x, v1.y, v3.x, v3.y, xmin, ymin) // 3-1
-	w3Row := Edge(v2.x, v2.y, v1.x, v1.y, xmin, ymin) // 1-2
-
-	incX23 := v2.y - v3.y
-	incX31 := v3.y - v1.y
-	incX12 := v1.y - v2.y
-	incY23 := v3.x - v2.x
-	incY31 := v1.x - v3.x
-	incY12 := v2.x - v1.x
-
-	for y := ymin; y <= ymax; y += 1 {
-		w1 := w1Row
-		w2 := w2Row
-		w3 := w3Row
-
-		for x := xmin; x <= xmax; x += 1 {
-			if gpu.drawUnmaskedPixels {
-				pix := uint32(gpu.vram.Read16(x, y))
-
-				if TestBit(pix, 15) {
-					// masked
-					w1 += incX23
-					w2 += incX31
-					w3 += incX12
-					continue
-				}
-			}
-
-			if (w1 > 0 || (w1 == 0 && topLeft23)) &&
-				(w2 > 0 || (w2 == 0 && topLeft31)) &&
-				(w3 > 0 || (w3 == 0 && topLeft12)) {
-				r := (w1*v1.r + w2*v2.r + w3*v3.r) / area
-				g := (w1*v1.g + w2*v2.g + w3*v3.g) / area
-				b := (w1*v1.b + w2*v2.b + w3*v3.b) / area
-
-				gpu.PutPixel(x, y, r, g, b, false, isSemiTransparent, stMode)
-			}
-
-			w1 += incX23
-			w2 += incX31
-			w3 += incX12
-		}
-
-		w1Row += incY23
-		w2Row += incY31
-		w3Row += incY12
-	}
-}
+package main
+
+const (
+	PATTR_RAW_TEXTURE = iota
+	PATTR_SEMI_TRANSPARENT
+	PATTR_TEXTURE
+	PATTR_QUAD
+	PATTR_GOURAUD
+)
+
+func (gpu *GPU) ProcessMonochromeQuadCommand() {
+	colour := gpu.fifo.buffer[0]
+
+	v1 := NewVertex(gpu.fifo.buffer[1], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[2], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[3], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v4 := NewVertex(gpu.fifo.buffer[4], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 2)
+	gpu.RefineVertex(v3, 3)
+	gpu.RefineVertex(v4, 4)
+
+	gpu.renderer.DrawTriangle(gpu, v1, v2, v3, gpu.semiTransparency, gpu.shape_attr)
+	gpu.renderer.DrawTriangle(gpu, v2, v3, v4, gpu.semiTransparency, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessShadedQuadCommand() {
+	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[3], gpu.fifo.buffer[2], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[5], gpu.fifo.buffer[4], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v4 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 3)
+	gpu.RefineVertex(v3, 5)
+	gpu.RefineVertex(v4, 7)
+
+	gpu.renderer.DrawTriangle(gpu, v1, v2, v3, gpu.semiTransparency, gpu.shape_attr)
+	gpu.renderer.DrawTriangle(gpu, v2, v3, v4, gpu.semiTransparency, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessTexturedQuadCommand() {
+	colour := gpu.fifo.buffer[0]
+
+	v1 := NewVertex(gpu.fifo.buffer[1], colour, gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[3], colour, gpu.fifo.buffer[4], gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[5], colour, gpu.fifo.buffer[6], gpu.drawingXOffset, gpu.drawingYOffset)
+	v4 := NewVertex(gpu.fifo.buffer[7], colour, gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 3)
+	gpu.RefineVertex(v3, 5)
+	gpu.RefineVertex(v4, 7)
+
+	clutIndex := gpu.fifo.buffer[2] >> 16
+	texPage := gpu.fifo.buffer[4] >> 16
+
+	clutX := int(GetRange(clutIndex, 0, 6) * 16)
+	clutY := int(GetRange(clutIndex, 6, 9))
+
+	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
+	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
+
+	stMode := int(GetRange(texPage, 5, 2))
+
+	texFormat := int(GetRange(texPage, 7, 2))
+
+	if texFormat == TEXTURE_FORMAT_reserved {
+		panic("[GPU::ProcessTexturedQuadCommand] reserved texture format")
+	}
+
+	gpu.renderer.DrawTexturedTriangle(gpu, v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+	gpu.renderer.DrawTexturedTriangle(gpu, v2, v3, v4, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessTexturedShadedQuadCommand() {
+	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[4], gpu.fifo.buffer[3], gpu.fifo.buffer[5], gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
+	v4 := NewVertex(gpu.fifo.buffer[10], gpu.fifo.buffer[9], gpu.fifo.buffer[11], gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 4)
+	gpu.RefineVertex(v3, 7)
+	gpu.RefineVertex(v4, 10)
+
+	clutIndex := gpu.fifo.buffer[2] >> 16
+	texPage := gpu.fifo.buffer[5] >> 16
+
+	clutX := int(GetRange(clutIndex, 0, 6) * 16)
+	clutY := int(GetRange(clutIndex, 6, 9))
+
+	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
+	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
+
+	stMode := int(GetRange(texPage, 5, 2))
+
+	texFormat := int(GetRange(texPage, 7, 2))
+
+	if texFormat == TEXTURE_FORMAT_reserved {
+		panic("[GPU::ProcessTexturedShadedQuadCommand] reserved texture format")
+	}
+
+	gpu.renderer.DrawTexturedTriangle(gpu, v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+	gpu.renderer.DrawTexturedTriangle(gpu, v2, v3, v4, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessMonochromeTrigCommand() {
+	colour := gpu.fifo.buffer[0]
+
+	v1 := NewVertex(gpu.fifo.buffer[1], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[2], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[3], colour, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 2)
+	gpu.RefineVertex(v3, 3)
+
+	gpu.renderer.DrawTriangle(gpu, v1, v2, v3, gpu.semiTransparency, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessShadedTrigCommand() {
+	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[3], gpu.fifo.buffer[2], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[5], gpu.fifo.buffer[4], 0, gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 3)
+	gpu.RefineVertex(v3, 5)
+
+	gpu.renderer.DrawTriangle(gpu, v1, v2, v3, gpu.semiTransparency, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessTexturedTrigCommand() {
+	colour := gpu.fifo.buffer[0]
+
+	v1 := NewVertex(gpu.fifo.buffer[1], colour, gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[3], colour, gpu.fifo.buffer[4], gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[5], colour, gpu.fifo.buffer[6], gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 3)
+	gpu.RefineVertex(v3, 5)
+
+	clutIndex := gpu.fifo.buffer[2] >> 16
+	texPage := gpu.fifo.buffer[4] >> 16
+
+	clutX := int(GetRange(clutIndex, 0, 6) * 16)
+	clutY := int(GetRange(clutIndex, 6, 9))
+
+	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
+	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
+
+	stMode := int(GetRange(texPage, 5, 2))
+
+	texFormat := int(GetRange(texPage, 7, 2))
+
+	if texFormat == TEXTURE_FORMAT_reserved {
+		panic("[GPU::ProcessTexturedTrigCommand] reserved texture format")
+	}
+
+	gpu.renderer.DrawTexturedTriangle(gpu, v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+}
+
+func (gpu *GPU) ProcessTexturedShadedTrigCommand() {
+	v1 := NewVertex(gpu.fifo.buffer[1], gpu.fifo.buffer[0], gpu.fifo.buffer[2], gpu.drawingXOffset, gpu.drawingYOffset)
+	v2 := NewVertex(gpu.fifo.buffer[4], gpu.fifo.buffer[3], gpu.fifo.buffer[5], gpu.drawingXOffset, gpu.drawingYOffset)
+	v3 := NewVertex(gpu.fifo.buffer[7], gpu.fifo.buffer[6], gpu.fifo.buffer[8], gpu.drawingXOffset, gpu.drawingYOffset)
+
+	gpu.RefineVertex(v1, 1)
+	gpu.RefineVertex(v2, 4)
+	gpu.RefineVertex(v3, 7)
+
+	clutIndex := gpu.fifo.buffer[2] >> 16
+	texPage := gpu.fifo.buffer[5] >> 16
+
+	clutX := int(GetRange(clutIndex, 0, 6) * 16)
+	clutY := int(GetRange(clutIndex, 6, 9))
+
+	texPageUBase := int(GetRange(texPage, 0, 4) * 64)
+	texPageVBase := int(GetRange(texPage, 4, 1) * 256)
+
+	stMode := int(GetRange(texPage, 5, 2))
+
+	texFormat := int(GetRange(texPage, 7, 2))
+
+	if texFormat == TEXTURE_FORMAT_reserved {
+		panic("[GPU::ProcessTexturedShadedTrigCommand] reserved texture format")
+	}
+
+	gpu.renderer.DrawTexturedTriangle(gpu, v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, gpu.shape_attr)
+}
+
+/*
+	https://psx-spx.consoledev.net/graphicsprocessingunitgpu/#notes-on-polygons
+
+Polygons are drawn with either winding. The right-most and bottom edges aren't drawn (top-left fill rule)
+so adjoining polygons sharing an edge never draw the same pixel twice.
+
+Triangles are drawn one scanline at a time: the span of each line is found from the edge functions (see
+TriangleEdges) and colour and texture coordinates are stepped with fixed-point gradients (see
+AttributeGradient) which are computed once per triangle.
+*/
+func (gpu *GPU) RenderTexturedTriangle(v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	if v1.precise != nil && v2.precise != nil && v3.precise != nil {
+		gpu.RenderPreciseTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, attr)
+		return
+	}
+
+	if Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y) < 0 {
+		// counter-clockwise; edge functions below expect clockwise vertexes
+		v2, v3 = v3, v2
+	}
+
+	xmin, ymin, xmax, ymax, ok := gpu.ClipTriangle(v1, v2, v3)
+	if !ok {
+		return
+	}
+
+	isRawTexture := TestBit(attr, PATTR_RAW_TEXTURE)
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	edges := NewTriangleEdges(v1, v2, v3)
+
+	gradU := NewAttributeGradient(v1, v2, v3, v1.u, v2.u, v3.u)
+	gradV := NewAttributeGradient(v1, v2, v3, v1.v, v2.v, v3.v)
+	gradR := NewAttributeGradient(v1, v2, v3, v1.r, v2.r, v3.r)
+	gradG := NewAttributeGradient(v1, v2, v3, v1.g, v2.g, v3.g)
+	gradB := NewAttributeGradient(v1, v2, v3, v1.b, v2.b, v3.b)
+
+	for y := ymin; y <= ymax; y += 1 {
+		if !gpu.OwnsLine(y) {
+			// another render thread draws this line
+			continue
+		}
+
+		x1, x2 := edges.Span(y, xmin, xmax)
+		if x1 > x2 {
+			continue
+		}
+
+		// fixed-point attributes at the start of the span
+		u := gradU.At(x1, y)
+		v := gradV.At(x1, y)
+		r := gradR.At(x1, y)
+		g := gradG.At(x1, y)
+		b := gradB.At(x1, y)
+
+		for x := x1; x <= x2; x += 1 {
+			texel := gpu.GetTexel(
+				Clamp8(u>>GRADIENT_FRAC_BITS),
+				Clamp8(v>>GRADIENT_FRAC_BITS),
+				clutX,
+				clutY,
+				texPageUBase,
+				texPageVBase,
+				texFormat,
+			)
+
+			if texel > 0 {
+				tr := int(GetRange(texel, 0, 5) << 3)
+				tg := int(GetRange(texel, 5, 5) << 3)
+				tb := int(GetRange(texel, 10, 5) << 3)
+				stp := TestBit(texel, 15)
+
+				// TODO texture masking?
+
+				if !isRawTexture {
+					tr, tg, tb = gpu.TextureBlend(
+						Clamp8(r>>GRADIENT_FRAC_BITS),
+						Clamp8(g>>GRADIENT_FRAC_BITS),
+						Clamp8(b>>GRADIENT_FRAC_BITS),
+						tr,
+						tg,
+						tb,
+					)
+				}
+
+				/* Summary of semi-transparency handling: https://github.com/ABelliqueux/nolibgs_hello_worlds/wiki/TIM#transparency
+
+				semi-transparency mode is set thru bit 25 of command
+				16-bit texel (format: STP, B, G, R) | semi-transparency mode off  | semi-transparency mode on
+				(0, 0, 0, 0)                          transparent (no draw)         transparent (no draw)
+				(1, 0, 0, 0)                          non-transparent (draw black)  semi-transparent
+				(0, n, n, n)                          non-transparent               non-transparent
+				(1, n, n, n)                          non-transparent               semi-transparent
+				*/
+				gpu.PutPixel(x, y, tr, tg, tb, stp, isSemiTransparent && stp, stMode)
+			}
+
+			u += gradU.dx
+			v += gradV.dx
+			r += gradR.dx
+			g += gradG.dx
+			b += gradB.dx
+		}
+	}
+}
+
+func (gpu *GPU) RenderTriangle(v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	if v1.precise != nil && v2.precise != nil && v3.precise != nil {
+		gpu.RenderPreciseTriangle(v1, v2, v3, stMode, attr)
+		return
+	}
+
+	if Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y) < 0 {
+		// counter-clockwise; edge functions below expect clockwise vertexes
+		v2, v3 = v3, v2
+	}
+
+	xmin, ymin, xmax, ymax, ok := gpu.ClipTriangle(v1, v2, v3)
+	if !ok {
+		return
+	}
+
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	edges := NewTriangleEdges(v1, v2, v3)
+
+	gradR := NewAttributeGradient(v1, v2, v3, v1.r, v2.r, v3.r)
+	gradG := NewAttributeGradient(v1, v2, v3, v1.g, v2.g, v3.g)
+	gradB := NewAttributeGradient(v1, v2, v3, v1.b, v2.b, v3.b)
+
+	for y := ymin; y <= ymax; y += 1 {
+		if !gpu.OwnsLine(y) {
+			// another render thread draws this line
+			continue
+		}
+
+		x1, x2 := edges.Span(y, xmin, xmax)
+		if x1 > x2 {
+			continue
+		}
+
+		// fixed-point colour at the start of the span
+		r := gradR.At(x1, y)
+		g := gradG.At(x1, y)
+		b := gradB.At(x1, y)
+
+		for x := x1; x <= x2; x += 1 {
+			gpu.PutPixel(x, y, Clamp8(r>>GRADIENT_FRAC_BITS), Clamp8(g>>GRADIENT_FRAC_BITS), Clamp8(b>>GRADIENT_FRAC_BITS), false, isSemiTransparent, stMode)
+
+			r += gradR.dx
+			g += gradG.dx
+			b += gradB.dx
+		}
+	}
+}
+
+/*
+	https://psx-spx.consoledev.net/graphicsprocessingunitgpu/#vertex-inputs
+
+Returns the bounding box of the triangle clipped to the drawing area. Polygons where the distance between
+two vertexes is larger than 1023 horizontally or 511 vertically are skipped by the gpu (ok = false).
+*/
+func (gpu *GPU) ClipTriangle(v1, v2, v3 *Vertex) (int, int, int, int, bool) {
+	xmin := MinOf(v1.x, v2.x, v3.x)
+	xmax := MaxOf(v1.x, v2.x, v3.x)
+	ymin := MinOf(v1.y, v2.y, v3.y)
+	ymax := MaxOf(v1.y, v2.y, v3.y)
+
+	if xmax-xmin >= gpu.vram.width || ymax-ymin >= gpu.vram.height {
+		return 0, 0, 0, 0, false
+	}
+
+	if Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y) == 0 {
+		// degenerate triangle; covers no pixels
+		return 0, 0, 0, 0, false
+	}
+
+	xmin = MaxOf(xmin, gpu.drawingAreaX1)
+	ymin = MaxOf(ymin, gpu.drawingAreaY1)
+	xmax = MinOf(xmax, gpu.drawingAreaX2)
+	ymax = MinOf(ymax, gpu.drawingAreaY2)
+
+	return xmin, ymin, xmax, ymax, xmin <= xmax && ymin <= ymax
+}
+
+const GRADIENT_FRAC_BITS = 12
+
+/*
+Linear interpolation of a vertex attribute (colour component or texture coordinate) over a triangle:
+
+	a(x, y) = a1 + dx*(x - x1) + dy*(y - y1)
+
+dx and dy are fixed-point (GRADIENT_FRAC_BITS fractional bits) and computed once per triangle like the gpu
+does; values are biased by one half so shifting them right rounds to the nearest integer.
+*/
+type AttributeGradient struct {
+	x1, y1 int
+	base   int
+	dx, dy int
+}
+
+func NewAttributeGradient(v1, v2, v3 *Vertex, a1, a2, a3 int) *AttributeGradient {
+	// twice the signed area of the triangle (non-zero, degenerate triangles are rejected by ClipTriangle)
+	denom := (v2.x-v1.x)*(v3.y-v1.y) - (v3.x-v1.x)*(v2.y-v1.y)
+
+	dx := (((a2 - a1) * (v3.y - v1.y)) - ((a3 - a1) * (v2.y - v1.y))) << GRADIENT_FRAC_BITS / denom
+	dy := (((a3 - a1) * (v2.x - v1.x)) - ((a2 - a1) * (v3.x - v1.x))) << GRADIENT_FRAC_BITS / denom
+
+	return &AttributeGradient{
+		v1.x,
+		v1.y,
+		a1<<GRADIENT_FRAC_BITS + 1<<(GRADIENT_FRAC_BITS-1),
+		dx,
+		dy,
+	}
+}
+
+/* fixed-point value at (x, y); step along a span by adding dx */
+func (grad *AttributeGradient) At(x, y int) int {
+	return grad.base + grad.dx*(x-grad.x1) + grad.dy*(y-grad.y1)
+}
+
+/*
+Edge functions of a clockwise triangle. Edge i is w(x, y) = a*x + b*y + c (see Edge) and a pixel is
+inside the triangle if all of them are > 0, or = 0 for pixels exactly on a top or left edge.
+*/
+type TriangleEdges struct {
+	a, b, c [3]int
+	bias    [3]int /* 0 for top-left edges, 1 otherwise (w >= bias means inside) */
+}
+
+func NewTriangleEdges(v1, v2, v3 *Vertex) *TriangleEdges {
+	edges := &TriangleEdges{}
+
+	// edges opposite of v1, v2 and v3
+	for i, e := range [3][2]*Vertex{{v3, v2}, {v1, v3}, {v2, v1}} {
+		from, to := e[0], e[1]
+
+		// Edge(from.x, from.y, to.x, to.y, x, y) expanded
+		edges.a[i] = to.y - from.y
+		edges.b[i] = -(to.x - from.x)
+		edges.c[i] = -from.x*(to.y-from.y) + from.y*(to.x-from.x)
+
+		if !IsTopLeft(to, from) {
+			edges.bias[i] = 1
+		}
+	}
+
+	return edges
+}
+
+/*
+Returns the first and last pixel of line y inside the triangle, limited to xmin..xmax; the span is empty
+if first > last
+*/
+func (edges *TriangleEdges) Span(y, xmin, xmax int) (int, int) {
+	for i := 0; i < 3; i += 1 {
+		a := edges.a[i]
+		// a*x + rest >= bias
+		rest := edges.b[i]*y + edges.c[i] - edges.bias[i]
+
+		switch {
+		case a > 0:
+			xmin = MaxOf(xmin, CeilDiv(-rest, a))
+		case a < 0:
+			xmax = MinOf(xmax, FloorDiv(rest, -a))
+		case rest < 0:
+			// horizontal edge and the line is outside of it
+			return 0, -1
+		}
+	}
+
+	return xmin, xmax
+}
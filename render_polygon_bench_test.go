@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+var gpuTrace = flag.String("gpu-trace", "", "gpu trace (see -record-trace) replayed by the rasterizer benchmarks, e.g. of PSXNICCC")
+
+/*
+The rasterizer from before triangles were drawn in spans: every pixel of the bounding box is tested
+against the edge functions and the attributes are computed from scratch for every pixel drawn. Kept to
+benchmark (and check) the span rasterizer against.
+*/
+type BoundingBoxRenderer struct {
+	SoftwareRenderer
+}
+
+func (renderer *BoundingBoxRenderer) DrawTriangle(gpu *GPU, v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	if v1.precise != nil && v2.precise != nil && v3.precise != nil {
+		gpu.RenderPreciseTriangle(v1, v2, v3, stMode, attr)
+		return
+	}
+
+	if Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y) < 0 {
+		v2, v3 = v3, v2
+	}
+
+	if _, _, _, _, ok := gpu.ClipTriangle(v1, v2, v3); !ok {
+		return
+	}
+
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	gradR := NewAttributeGradient(v1, v2, v3, v1.r, v2.r, v3.r)
+	gradG := NewAttributeGradient(v1, v2, v3, v1.g, v2.g, v3.g)
+	gradB := NewAttributeGradient(v1, v2, v3, v1.b, v2.b, v3.b)
+
+	BoundingBoxRasterize(gpu, v1, v2, v3, func(x, y int) {
+		r := Clamp8(gradR.At(x, y) >> GRADIENT_FRAC_BITS)
+		g := Clamp8(gradG.At(x, y) >> GRADIENT_FRAC_BITS)
+		b := Clamp8(gradB.At(x, y) >> GRADIENT_FRAC_BITS)
+
+		gpu.PutPixel(x, y, r, g, b, false, isSemiTransparent, stMode)
+	})
+}
+
+func (renderer *BoundingBoxRenderer) DrawTexturedTriangle(gpu *GPU, v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	if v1.precise != nil && v2.precise != nil && v3.precise != nil {
+		gpu.RenderPreciseTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, attr)
+		return
+	}
+
+	if Edge(v1.x, v1.y, v3.x, v3.y, v2.x, v2.y) < 0 {
+		v2, v3 = v3, v2
+	}
+
+	if _, _, _, _, ok := gpu.ClipTriangle(v1, v2, v3); !ok {
+		return
+	}
+
+	isRawTexture := TestBit(attr, PATTR_RAW_TEXTURE)
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	gradU := NewAttributeGradient(v1, v2, v3, v1.u, v2.u, v3.u)
+	gradV := NewAttributeGradient(v1, v2, v3, v1.v, v2.v, v3.v)
+	gradR := NewAttributeGradient(v1, v2, v3, v1.r, v2.r, v3.r)
+	gradG := NewAttributeGradient(v1, v2, v3, v1.g, v2.g, v3.g)
+	gradB := NewAttributeGradient(v1, v2, v3, v1.b, v2.b, v3.b)
+
+	BoundingBoxRasterize(gpu, v1, v2, v3, func(x, y int) {
+		u := Clamp8(gradU.At(x, y) >> GRADIENT_FRAC_BITS)
+		v := Clamp8(gradV.At(x, y) >> GRADIENT_FRAC_BITS)
+
+		texel := gpu.GetTexel(u, v, clutX, clutY, texPageUBase, texPageVBase, texFormat)
+		if texel == 0 {
+			return
+		}
+
+		tr := int(GetRange(texel, 0, 5) << 3)
+		tg := int(GetRange(texel, 5, 5) << 3)
+		tb := int(GetRange(texel, 10, 5) << 3)
+		stp := TestBit(texel, 15)
+
+		if !isRawTexture {
+			tr, tg, tb = gpu.TextureBlend(
+				Clamp8(gradR.At(x, y)>>GRADIENT_FRAC_BITS),
+				Clamp8(gradG.At(x, y)>>GRADIENT_FRAC_BITS),
+				Clamp8(gradB.At(x, y)>>GRADIENT_FRAC_BITS),
+				tr,
+				tg,
+				tb,
+			)
+		}
+
+		gpu.PutPixel(x, y, tr, tg, tb, stp, isSemiTransparent && stp, stMode)
+	})
+}
+
+/* calls pixel for every pixel of the bounding box of a clockwise triangle which is inside of it */
+func BoundingBoxRasterize(gpu *GPU, v1, v2, v3 *Vertex, pixel func(x, y int)) {
+	xmin, ymin, xmax, ymax, ok := gpu.ClipTriangle(v1, v2, v3)
+	if !ok {
+		return
+	}
+
+	topLeft12 := IsTopLeft(v1, v2) // v1-v2 edge
+	topLeft23 := IsTopLeft(v2, v3) // v2-v3 edge
+	topLeft31 := IsTopLeft(v3, v1) // v3-v1 edge
+
+	w1Row := Edge(v3.x, v3.y, v2.x, v2.y, xmin, ymin) // 2-3
+	w2Row := Edge(v1.x, v1.y, v3.x, v3.y, xmin, ymin) // 3-1
+	w3Row := Edge(v2.x, v2.y, v1.x, v1.y, xmin, ymin) // 1-2
+
+	incX23 := v2.y - v3.y
+	incX31 := v3.y - v1.y
+	incX12 := v1.y - v2.y
+	incY23 := v3.x - v2.x
+	incY31 := v1.x - v3.x
+	incY12 := v2.x - v1.x
+
+	for y := ymin; y <= ymax; y += 1 {
+		w1 := w1Row
+		w2 := w2Row
+		w3 := w3Row
+
+		for x := xmin; x <= xmax; x += 1 {
+			if (w1 > 0 || (w1 == 0 && topLeft23)) &&
+				(w2 > 0 || (w2 == 0 && topLeft31)) &&
+				(w3 > 0 || (w3 == 0 && topLeft12)) {
+				pixel(x, y)
+			}
+
+			w1 += incX23
+			w2 += incX31
+			w3 += incX12
+		}
+
+		w1Row += incY23
+		w2Row += incY31
+		w3Row += incY12
+	}
+}
+
+/*
+Stand-in for a recording of PSXNICCC when no -gpu-trace is given: frames of flat shaded triangles and
+quads of every size (a few large ones, lots of small and thin ones) with some gouraud and textured ones
+*/
+func DemoGP0Stream(seed int64, frames int) []uint32 {
+	random := rand.New(rand.NewSource(seed))
+
+	vertex := func(x, y, size int) uint32 {
+		return VertexWord(x+random.Intn(size)-size/2, y+random.Intn(size)-size/2)
+	}
+	colour := func() uint32 { return uint32(random.Intn(0x1000000)) }
+
+	stream := []uint32{}
+	for frame := 0; frame < frames; frame += 1 {
+		// clear the frame buffer
+		stream = append(stream, 0x02000000, VertexWord(0, 0), 240<<16|320)
+
+		for i := 0; i < 300; i += 1 {
+			size := []int{8, 16, 32, 64, 160}[random.Intn(5)]
+			x, y := random.Intn(320), random.Intn(240)
+
+			switch random.Intn(8) {
+			case 0, 1, 2:
+				stream = append(stream, 0x20000000|colour(), vertex(x, y, size), vertex(x, y, size), vertex(x, y, size))
+			case 3, 4, 5:
+				stream = append(stream, 0x28000000|colour(), vertex(x, y, size), vertex(x, y, size), vertex(x, y, size), vertex(x, y, size))
+			case 6:
+				stream = append(stream, 0x30000000|colour(), vertex(x, y, size), colour(), vertex(x, y, size), colour(), vertex(x, y, size))
+			case 7:
+				texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+				stream = append(stream, 0x24808080, vertex(x, y, size), 0x0000, vertex(x, y, size), texPage<<16|31, vertex(x, y, size), 31<<8)
+			}
+		}
+	}
+
+	return stream
+}
+
+/* replays -gpu-trace or DemoGP0Stream on a gpu drawing with renderer */
+func ReplayWithRenderer(tb testing.TB, renderer Renderer, trace []byte) *GPU {
+	gpu := NewTestGPU()
+	gpu.SetRenderer(renderer)
+
+	if trace != nil {
+		if err := gpu.ReplayTrace(bytes.NewReader(trace), nil); err != nil {
+			tb.Fatal(err)
+		}
+		gpu.WaitForIdle()
+		gpu.renderer.SyncVRAM(gpu.vram)
+		return gpu
+	}
+
+	UploadTestTexture(gpu)
+	SendGP0(gpu, DemoGP0Stream(1, 4)...)
+	gpu.renderer.SyncVRAM(gpu.vram)
+
+	return gpu
+}
+
+func ReadGPUTrace(tb testing.TB) []byte {
+	if *gpuTrace == "" {
+		return nil
+	}
+
+	trace, err := os.ReadFile(*gpuTrace)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return trace
+}
+
+func TestSpansMatchBoundingBox(t *testing.T) {
+	trace := ReadGPUTrace(t)
+
+	CompareVRAM(t, ReplayWithRenderer(t, NewSoftwareRenderer(), trace), ReplayWithRenderer(t, &BoundingBoxRenderer{}, trace))
+}
+
+/*
+go test -bench Rasterizer [-gpu-trace psxniccc.trace]; a trace is recorded with the emulator's -record-trace
+*/
+func BenchmarkRasterizer(b *testing.B) {
+	trace := ReadGPUTrace(b)
+
+	renderers := []struct {
+		name     string
+		renderer func() Renderer
+	}{
+		{"spans", func() Renderer { return NewSoftwareRenderer() }},
+		{"boundingbox", func() Renderer { return &BoundingBoxRenderer{} }},
+	}
+
+	for _, renderer := range renderers {
+		b.Run(renderer.name, func(b *testing.B) {
+			for i := 0; i < b.N; i += 1 {
+				ReplayWithRenderer(b, renderer.renderer(), trace)
+			}
+		})
+	}
+}
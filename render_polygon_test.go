@@ -0,0 +1,282 @@
+package main
+
+import (
+	"testing"
+)
+
+/* vram pixels which aren't zero in the area */
+func DrawnPixels(gpu *GPU, x1, y1, x2, y2 int) map[[2]int]uint16 {
+	drawn := make(map[[2]int]uint16)
+
+	for y := y1; y <= y2; y += 1 {
+		for x := x1; x <= x2; x += 1 {
+			if pixel := gpu.vram.Read16(x, y); pixel != 0 {
+				drawn[[2]int{x, y}] = pixel
+			}
+		}
+	}
+
+	return drawn
+}
+
+func TestClipTriangleRejectsLargePolygons(t *testing.T) {
+	tests := []struct {
+		name       string
+		x, y       [3]int
+		expectDraw bool
+	}{
+		{"1023 wide", [3]int{0, 1023, 0}, [3]int{0, 0, 10}, true},
+		{"1024 wide", [3]int{-1, 1023, 0}, [3]int{0, 0, 10}, false},
+		{"511 high", [3]int{0, 10, 0}, [3]int{0, 0, 511}, true},
+		{"512 high", [3]int{0, 10, 0}, [3]int{-1, 0, 511}, false},
+		{"degenerate", [3]int{0, 10, 20}, [3]int{0, 10, 20}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gpu := NewTestGPU()
+
+			v := [3]*Vertex{}
+			for i := range v {
+				v[i] = NewVertex(VertexWord(test.x[i], test.y[i]), 0, 0, 0, 0)
+			}
+
+			if _, _, _, _, ok := gpu.ClipTriangle(v[0], v[1], v[2]); ok != test.expectDraw {
+				t.Errorf("ClipTriangle says %t, expected %t", ok, test.expectDraw)
+			}
+
+			SendGP0(gpu, 0x20ffffff, VertexWord(test.x[0], test.y[0]), VertexWord(test.x[1], test.y[1]), VertexWord(test.x[2], test.y[2]))
+
+			if drawn := len(DrawnPixels(gpu, 0, 0, VRAM_WIDTH-1, VRAM_HEIGHT-1)) > 0; drawn != test.expectDraw {
+				t.Errorf("drawn is %t, expected %t", drawn, test.expectDraw)
+			}
+		})
+	}
+}
+
+func TestTopLeftFillRule(t *testing.T) {
+	gpu := NewTestGPU()
+
+	// the right-most and bottom edges aren't drawn: pixels with x+y=4 are on the hypotenuse
+	SendGP0(gpu, 0x20ffffff, VertexWord(10, 10), VertexWord(14, 10), VertexWord(10, 14))
+
+	drawn := DrawnPixels(gpu, 0, 0, 31, 31)
+	for y := 0; y < 32; y += 1 {
+		for x := 0; x < 32; x += 1 {
+			_, ok := drawn[[2]int{x, y}]
+			inside := x >= 10 && y >= 10 && (x-10)+(y-10) < 4
+
+			if ok != inside {
+				t.Errorf("(%d,%d) drawn is %t, expected %t", x, y, ok, inside)
+			}
+		}
+	}
+
+	// a (0,0)-(32,32) quad covers (0,0)-(31,31)
+	gpu = NewTestGPU()
+	SendGP0(gpu, 0x28ffffff, VertexWord(100, 100), VertexWord(132, 100), VertexWord(100, 132), VertexWord(132, 132))
+
+	if drawn := DrawnPixels(gpu, 90, 90, 140, 140); len(drawn) != 32*32 {
+		t.Errorf("%d pixels drawn, expected %d", len(drawn), 32*32)
+	}
+	for _, corner := range [][2]int{{100, 100}, {131, 100}, {100, 131}, {131, 131}} {
+		if gpu.vram.Read16(corner[0], corner[1]) == 0 {
+			t.Errorf("%v isn't drawn", corner)
+		}
+	}
+}
+
+/* triangles sharing edges are drawn additively; a pixel drawn twice would be twice as bright */
+func TestSharedEdgesAreDrawnOnce(t *testing.T) {
+	gpu := NewTestGPU()
+
+	// semi-transparency mode 1 (B+F)
+	SendGP0(gpu, 0xe1000000|1<<5)
+
+	centre := VertexWord(200, 150)
+	points := [][2]int{{200, 50}, {290, 80}, {310, 160}, {260, 250}, {150, 240}, {95, 170}, {120, 60}}
+
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		SendGP0(gpu, 0x22080808, centre, VertexWord(a[0], a[1]), VertexWord(b[0], b[1]))
+	}
+
+	// and a quad with a diagonal that isn't axis aligned
+	SendGP0(gpu, 0x2a080808, VertexWord(410, 13), VertexWord(470, 20), VertexWord(402, 90), VertexWord(481, 77))
+
+	drawn := DrawnPixels(gpu, 0, 0, 511, 299)
+	if len(drawn) == 0 {
+		t.Fatal("nothing drawn")
+	}
+
+	single := RGB15(1, 1, 1)
+	for pixel, value := range drawn {
+		if value != single {
+			t.Fatalf("%v is %04x, expected %04x", pixel, value, single)
+		}
+	}
+}
+
+func TestTrianglesAreClippedToTheDrawingArea(t *testing.T) {
+	gpu := NewTestGPU()
+
+	SendGP0(
+		gpu,
+		0xe3000000|20<<10|30, // (30,20)
+		0xe4000000|40<<10|60, // (60,40)
+		0x28ffffff, VertexWord(0, 0), VertexWord(100, 0), VertexWord(0, 100), VertexWord(100, 100),
+	)
+
+	drawn := DrawnPixels(gpu, 0, 0, 127, 127)
+	if len(drawn) != 31*21 {
+		t.Errorf("%d pixels drawn, expected %d", len(drawn), 31*21)
+	}
+	for pixel := range drawn {
+		if pixel[0] < 30 || pixel[0] > 60 || pixel[1] < 20 || pixel[1] > 40 {
+			t.Errorf("%v is outside of the drawing area", pixel)
+		}
+	}
+}
+
+func TestGouraudInterpolation(t *testing.T) {
+	gpu := NewTestGPU()
+
+	// red goes 0..255 over 255 pixels: one step per pixel, exactly
+	SendGP0(gpu, 0x38000000, VertexWord(0, 0), 0x0000ff, VertexWord(255, 0), 0x000000, VertexWord(0, 8), 0x0000ff, VertexWord(255, 8))
+
+	for x := 0; x < 255; x += 1 {
+		if red := int(gpu.vram.Read16(x, 4) & 0x1f); red != x>>3 {
+			t.Fatalf("red at x=%d is %d, expected %d", x, red, x>>3)
+		}
+	}
+
+	// a slope that isn't a whole number: within one step of the exact value and never going backwards
+	gpu = NewTestGPU()
+	SendGP0(gpu, 0x30000000, VertexWord(0, 0), 0x0000f8, VertexWord(93, 0), 0x000000, VertexWord(0, 50))
+
+	previous := 0
+	for x := 0; x < 80; x += 1 {
+		red := int(gpu.vram.Read16(x, 0) & 0x1f)
+		exact := float64(x) * 31 / 93
+
+		if float64(red) < exact-1 || float64(red) > exact+1 || red < previous {
+			t.Fatalf("red at x=%d is %d, exact value is %.2f", x, red, exact)
+		}
+		previous = red
+	}
+}
+
+func TestTextureCoordinateInterpolation(t *testing.T) {
+	gpu := NewTestGPU()
+	UploadTestTexture(gpu)
+
+	texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+
+	// u 0..32 over 64 pixels and v 0..32 over 64 lines; coordinates are rounded to the nearest texel since
+	// the gradients start with a bias of one half, so texel n covers pixels 2n-1 and 2n
+	SendGP0(
+		gpu,
+		0x2d000000,
+		VertexWord(0, 0), 0x0000,
+		VertexWord(64, 0), texPage<<16|32,
+		VertexWord(0, 64), 32<<8,
+		VertexWord(64, 64), 32<<8|32,
+	)
+
+	for y := 0; y < 64; y += 1 {
+		for x := 0; x < 64; x += 1 {
+			u, v := (x+1)/2, (y+1)/2
+
+			if pixel, texel := gpu.vram.Read16(x, y), gpu.vram.Read16(640+u, v); pixel != texel {
+				t.Fatalf("(%d,%d) is %04x, expected texel (%d,%d) %04x", x, y, pixel, u, v, texel)
+			}
+		}
+	}
+}
+
+func TestPolygonGolden(t *testing.T) {
+	gpu := NewTestGPU()
+	UploadTestTexture(gpu)
+
+	texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+
+	words := []uint32{
+		0xe3000000 | 4<<10 | 4,     // (4,4)
+		0xe4000000 | 123<<10 | 251, // (251,123)
+
+		// gouraud quad with a sliver triangle next to it
+		0x38ff0000, VertexWord(-10, 0), 0x00ff00, VertexWord(120, 10), 0x0000ff, VertexWord(0, 110), 0xffffff, VertexWord(140, 130),
+		0x30ffff00, VertexWord(121, 10), 0x00ffff, VertexWord(141, 130), 0xff00ff, VertexWord(125, 0),
+
+		// blended textured triangles, one of them rotated
+		0x24808080, VertexWord(150, 10), 0x0000, VertexWord(250, 20), texPage<<16 | 31, VertexWord(160, 60), 31 << 8,
+		0x3c4080ff, VertexWord(200, 70), 0x0000, 0x80ff40, VertexWord(255, 90), texPage<<16 | 31, 0xff8040, VertexWord(170, 110), 31 << 8, 0x808080, VertexWord(230, 130), 31<<8 | 31,
+	}
+
+	for i := 0; i < 8; i += 1 {
+		// thin triangles in every direction
+		x, y := 20+i*14, 120
+		words = append(words, 0x20ffffff^uint32(i*0x1f1f), VertexWord(x, y), VertexWord(x+3+i, y-30+i*3), VertexWord(x+1, y-i))
+	}
+
+	SendGP0(gpu, words...)
+
+	// worked out by hand from the vertexes: the drawing area is inclusive on all sides, the bottom edges of
+	// the thin triangles (y=120) aren't drawn and the gouraud quad only covers row 120 from x=70 on
+	for x := 0; x < 256; x += 1 {
+		if pixel := gpu.vram.Read16(x, 3); pixel != 0 {
+			t.Errorf("(%d,3) above the drawing area is %04x", x, pixel)
+		}
+		if pixel := gpu.vram.Read16(x, 124); pixel != 0 {
+			t.Errorf("(%d,124) below the drawing area is %04x", x, pixel)
+		}
+		if pixel := gpu.vram.Read16(x, 120); x >= 20 && x < 70 && pixel != 0 {
+			t.Errorf("(%d,120) on the bottom edge of a thin triangle is %04x", x, pixel)
+		}
+	}
+	for y := 0; y < 128; y += 1 {
+		if pixel := gpu.vram.Read16(3, y); pixel != 0 {
+			t.Errorf("(3,%d) left of the drawing area is %04x", y, pixel)
+		}
+		if pixel := gpu.vram.Read16(252, y); pixel != 0 {
+			t.Errorf("(252,%d) right of the drawing area is %04x", y, pixel)
+		}
+	}
+	for _, pixel := range [][2]int{{4, 4}, {4, 100}, {251, 90}, {230, 123}} {
+		if gpu.vram.Read16(pixel[0], pixel[1]) == 0 {
+			t.Errorf("%v on the border of the drawing area isn't drawn", pixel)
+		}
+	}
+	// the first thin triangle covers x=20.1..21.07 on row 119, which is only pixel 21
+	if drawn := DrawnPixels(gpu, 16, 119, 30, 119); len(drawn) != 1 || drawn[[2]int{21, 119}] != 0x7fff {
+		t.Errorf("row 119 of the first thin triangle is %v, expected only (21,119) to be white", drawn)
+	}
+
+	CheckGolden(t, "polygons", VRAMImage(gpu, 0, 0, 256, 128))
+}
+
+func TestTriangleEdgesSpan(t *testing.T) {
+	v1 := NewVertex(VertexWord(0, 0), 0, 0, 0, 0)
+	v2 := NewVertex(VertexWord(8, 0), 0, 0, 0, 0)
+	v3 := NewVertex(VertexWord(0, 8), 0, 0, 0, 0)
+
+	edges := NewTriangleEdges(v1, v2, v3)
+
+	for y := 0; y < 8; y += 1 {
+		if x1, x2 := edges.Span(y, 0, 8); x1 != 0 || x2 != 7-y {
+			t.Errorf("span of line %d is %d..%d, expected 0..%d", y, x1, x2, 7-y)
+		}
+	}
+
+	// limited to the bounding box
+	if x1, x2 := edges.Span(2, 3, 4); x1 != 3 || x2 != 4 {
+		t.Errorf("span is %d..%d, expected 3..4", x1, x2)
+	}
+
+	// lines outside of the triangle are empty
+	for _, y := range []int{-1, 8} {
+		if x1, x2 := edges.Span(y, 0, 8); x1 <= x2 {
+			t.Errorf("span of line %d is %d..%d, expected it to be empty", y, x1, x2)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import "math"
+
+/*
+Precision geometry version of GPU::RenderTriangle (see PGXP); only used when all three vertexes have a
+precise position. Colours are interpolated affinely like the gpu does.
+*/
+func (gpu *GPU) RenderPreciseTriangle(v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	gpu.RasterizePrecise(v1, v2, v3, func(v1, v2, v3 *Vertex, x, y int, l1, l2, l3, iw1, iw2, iw3 float64) {
+		r := RoundToInt8(l1*float64(v1.r) + l2*float64(v2.r) + l3*float64(v3.r))
+		g := RoundToInt8(l1*float64(v1.g) + l2*float64(v2.g) + l3*float64(v3.g))
+		b := RoundToInt8(l1*float64(v1.b) + l2*float64(v2.b) + l3*float64(v3.b))
+
+		gpu.PutPixel(x, y, r, g, b, false, isSemiTransparent, stMode)
+	})
+}
+
+/*
+Precision geometry version of GPU::RenderTexturedTriangle. Texture coordinates are interpolated
+perspective correctly, ie. u/w, v/w and 1/w are interpolated linearly over the screen and u and v are
+recovered per pixel. If any depth is unknown it falls back to affine interpolation (still with sub-pixel
+positions).
+*/
+func (gpu *GPU) RenderPreciseTexturedTriangle(v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	isRawTexture := TestBit(attr, PATTR_RAW_TEXTURE)
+	isSemiTransparent := TestBit(attr, PATTR_SEMI_TRANSPARENT)
+
+	gpu.RasterizePrecise(v1, v2, v3, func(v1, v2, v3 *Vertex, x, y int, l1, l2, l3, iw1, iw2, iw3 float64) {
+		iw := l1*iw1 + l2*iw2 + l3*iw3
+		u := (l1*float64(v1.u)*iw1 + l2*float64(v2.u)*iw2 + l3*float64(v3.u)*iw3) / iw
+		v := (l1*float64(v1.v)*iw1 + l2*float64(v2.v)*iw2 + l3*float64(v3.v)*iw3) / iw
+
+		texel := gpu.GetTexel(RoundToInt8(u), RoundToInt8(v), clutX, clutY, texPageUBase, texPageVBase, texFormat)
+		if texel == 0 {
+			return
+		}
+
+		tr := int(GetRange(texel, 0, 5) << 3)
+		tg := int(GetRange(texel, 5, 5) << 3)
+		tb := int(GetRange(texel, 10, 5) << 3)
+		stp := TestBit(texel, 15)
+
+		if !isRawTexture {
+			tr, tg, tb = gpu.TextureBlend(
+				RoundToInt8(l1*float64(v1.r)+l2*float64(v2.r)+l3*float64(v3.r)),
+				RoundToInt8(l1*float64(v1.g)+l2*float64(v2.g)+l3*float64(v3.g)),
+				RoundToInt8(l1*float64(v1.b)+l2*float64(v2.b)+l3*float64(v3.b)),
+				tr,
+				tg,
+				tb,
+			)
+		}
+
+		gpu.PutPixel(x, y, tr, tg, tb, stp, isSemiTransparent && stp, stMode)
+	})
+}
+
+/*
+Calls pixel for every pixel inside of the triangle thru the sub-pixel vertex positions (so polygons don't
+snap to whole pixels) with the vertexes in clockwise order, the barycentric coordinates of the pixel and
+the reciprocal depths of the vertexes (1 if any depth is unknown, which makes interpolation affine).
+
+The integer vertexes still decide whether the gpu draws the triangle at all (see GPU::ClipTriangle).
+*/
+func (gpu *GPU) RasterizePrecise(v1, v2, v3 *Vertex, pixel func(v1, v2, v3 *Vertex, x, y int, l1, l2, l3, iw1, iw2, iw3 float64)) {
+	p1, p2, p3 := v1.precise, v2.precise, v3.precise
+
+	area := PreciseEdge(p1, p3, p2.x, p2.y)
+	if area == 0 {
+		return
+	}
+
+	if area < 0 {
+		// counter-clockwise; edge functions below expect clockwise vertexes
+		v2, v3 = v3, v2
+		p2, p3 = p3, p2
+		area = -area
+	}
+
+	if _, _, _, _, ok := gpu.ClipTriangle(v1, v2, v3); !ok {
+		return
+	}
+
+	// sub-pixel positions may be up to a pixel away from the integer ones
+	xmin := MaxOf(int(math.Floor(math.Min(p1.x, math.Min(p2.x, p3.x)))), gpu.drawingAreaX1)
+	ymin := MaxOf(int(math.Floor(math.Min(p1.y, math.Min(p2.y, p3.y)))), gpu.drawingAreaY1)
+	xmax := MinOf(int(math.Ceil(math.Max(p1.x, math.Max(p2.x, p3.x)))), gpu.drawingAreaX2)
+	ymax := MinOf(int(math.Ceil(math.Max(p1.y, math.Max(p2.y, p3.y)))), gpu.drawingAreaY2)
+
+	topLeft12 := IsPreciseTopLeft(p1, p2) // v1-v2 edge
+	topLeft23 := IsPreciseTopLeft(p2, p3) // v2-v3 edge
+	topLeft31 := IsPreciseTopLeft(p3, p1) // v3-v1 edge
+
+	iw1, iw2, iw3 := 1.0, 1.0, 1.0
+	if p1.w > 0 && p2.w > 0 && p3.w > 0 {
+		iw1, iw2, iw3 = 1/p1.w, 1/p2.w, 1/p3.w
+	}
+
+	for y := ymin; y <= ymax; y += 1 {
+		if !gpu.OwnsLine(y) {
+			// another render thread draws this line
+			continue
+		}
+
+		fy := float64(y)
+
+		for x := xmin; x <= xmax; x += 1 {
+			fx := float64(x)
+
+			w1 := PreciseEdge(p3, p2, fx, fy) // 2-3
+			w2 := PreciseEdge(p1, p3, fx, fy) // 3-1
+			w3 := PreciseEdge(p2, p1, fx, fy) // 1-2
+
+			if !(w1 > 0 || (w1 == 0 && topLeft23)) ||
+				!(w2 > 0 || (w2 == 0 && topLeft31)) ||
+				!(w3 > 0 || (w3 == 0 && topLeft12)) {
+				continue
+			}
+
+			pixel(v1, v2, v3, x, y, w1/area, w2/area, w3/area, iw1, iw2, iw3)
+		}
+	}
+}
+
+/* see Edge */
+func PreciseEdge(p1, p2 *PreciseVertex, x, y float64) float64 {
+	return (x-p1.x)*(p2.y-p1.y) - (y-p1.y)*(p2.x-p1.x)
+}
+
+/* see IsTopLeft */
+func IsPreciseTopLeft(p1, p2 *PreciseVertex) bool {
+	return (p1.y == p2.y && p1.x < p2.x) || (p1.y > p2.y)
+}
+
+/* rounds to the nearest integer and clamps to 0..255 */
+func RoundToInt8(v float64) int {
+	return Clamp8(int(math.Floor(v + 0.5)))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestApplyTextureWindow(t *testing.T) {
+	tests := []struct {
+		name                           string
+		maskX, maskY, offsetX, offsetY int
+		u, v                           int
+		expectedU, expectedV           int
+	}{
+		{"no window", 0, 0, 0, 0, 123, 45, 123, 45},
+		{"offset without mask", 0, 0, 0x1f, 0x1f, 123, 45, 123, 45},
+		{"8 texel tile", 0x1f, 0x1f, 3, 5, 13, 200, 29, 40},
+		{"16 texel tile", 0x1e, 0x1e, 2, 4, 0xff, 0x0f, 0x1f, 0x2f},
+		{"32 texel tile", 0x1c, 0x1c, 4, 0, 0x45, 0x9a, 0x25, 0x1a},
+		{"offset bits outside of the mask are ignored", 0x02, 0x02, 0x03, 0x1d, 1, 1, 17, 1},
+		{"mask without offset clears the bits", 0x01, 0x10, 0, 0, 15, 0xff, 7, 0x7f},
+		{"only the masked bits change", 0x01, 0x01, 1, 1, 16, 0, 24, 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gpu := NewTestGPU()
+			SendGP0(gpu, 0xe2000000|uint32(test.maskX|test.maskY<<5|test.offsetX<<10|test.offsetY<<15))
+
+			u, v := gpu.ApplyTextureWindow(test.u, test.v)
+			if u != test.expectedU || v != test.expectedV {
+				t.Errorf("(%d,%d) became (%d,%d), expected (%d,%d)", test.u, test.v, u, v, test.expectedU, test.expectedV)
+			}
+		})
+	}
+}
+
+/* 32x32 15bit texture at (640,0); red and green are u and v, blue is an 8x8 checkerboard */
+func UploadTestTexture(gpu *GPU) {
+	pixels := make([]uint16, 32*32)
+	for v := 0; v < 32; v += 1 {
+		for u := 0; u < 32; u += 1 {
+			pixels[v*32+u] = RGB15(u, v, ((u/8+v/8)%2)*31)
+		}
+	}
+
+	UploadVRAM(gpu, 640, 0, 32, 32, pixels)
+}
+
+func TestTextureWindowGolden(t *testing.T) {
+	gpu := NewTestGPU()
+	UploadTestTexture(gpu)
+
+	texPage := uint32(640/64) | TEXTURE_FORMAT_15b<<7
+
+	SendGP0(
+		gpu,
+		0xe1000000|texPage,
+		// columns 16..31 repeated every 16 texels, rows 0..31 repeated every 32 texels
+		0xe2000000|0x1e|0x1c<<5|2<<10,
+		0x65000000, // textured variable size rectangle, raw texture
+		VertexWord(0, 0),
+		0x0000,
+		64<<16|64,
+		// columns 8..15 repeated every 8 texels, rows 0..15 repeated every 16 texels
+		0xe2000000|0x1f|0x1e<<5|1<<10,
+		0x2d000000, // textured quad, raw texture
+		VertexWord(64, 0),
+		0x0000,
+		VertexWord(128, 0),
+		texPage<<16|63,
+		VertexWord(64, 64),
+		63<<8,
+		VertexWord(128, 64),
+		63<<8|63,
+		// x and y windows which differ: columns 0..7 every 8 texels (bits 3-4 cleared), rows with bit 3 set
+		0xe2000000|0x03|0x01<<5|0x01<<15,
+		0x65000000,
+		VertexWord(0, 64),
+		0x0000,
+		32<<16|32,
+	)
+
+	// texels worked out by hand (the golden image only catches changes): the rectangle's (u,v) is its
+	// (x,y), which the first window turns into (16 + u%16, v%32); the quad's first column is u=0 -> 8; the
+	// last rectangle at (0,64) turns (u,v) into (u%8, v|8)
+	texels := []struct {
+		x, y int
+		u, v int
+	}{
+		{0, 0, 16, 0},
+		{5, 3, 21, 3},
+		{15, 31, 31, 31},
+		{16, 32, 16, 0},
+		{20, 40, 20, 8},
+		{47, 13, 31, 13},
+		{63, 63, 31, 31},
+		{64, 0, 8, 0},
+		{0, 64, 0, 8},
+		{16, 64, 0, 8},
+		{13, 66, 5, 10},
+		{10, 81, 2, 25},
+		{31, 95, 7, 31},
+	}
+
+	for _, texel := range texels {
+		expected := RGB15(texel.u, texel.v, ((texel.u/8+texel.v/8)%2)*31)
+		if pixel := gpu.vram.Read16(texel.x, texel.y) & 0x7fff; pixel != expected {
+			t.Errorf("(%d,%d) is %04x, expected texel (%d,%d) %04x", texel.x, texel.y, pixel, texel.u, texel.v, expected)
+		}
+	}
+
+	CheckGolden(t, "texture_window", VRAMImage(gpu, 0, 0, 128, 64))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+const RENDER_THREAD_QUEUE_SIZE = 256
+
+/*
+Threaded software renderer
+
+Every primitive is rendered by all render threads at once; thread i only draws the vram lines y
+where y mod n = i (lines are interleaved so that the work is spread evenly between the threads).
+Since each pixel is always touched by the same thread, semi-transparency and mask bit checks
+(which read back the pixel being drawn) see the same data as in the single-threaded renderer.
+
+A job is a snapshot of the gpu (including the argument fifo) taken when the command was received,
+so later environment commands (GP0(E1h)..GP0(E6h)) don't affect primitives which are still queued.
+
+Anything else that touches vram has to wait until the render threads are idle:
+- VRAM to CPU transfers (GPUREAD)
+- VRAM to VRAM copies and fills
+- CPU to VRAM transfers
+- textured primitives whose texture page or clut overlaps an area drawn since the last sync
+- primitives drawing over a texture page or clut which queued primitives are still reading from
+- the frontend reading vram at the end of a frame
+
+With texture cache emulation on, textured primitives are drawn on the emulation goroutine (after the
+threads are idle) since the state of the cache depends on the order of every texel fetch; only
+untextured primitives are handed to the threads then.
+*/
+type RenderThreads struct {
+	n    int
+	jobs []chan *GPU
+	wg   sync.WaitGroup
+
+	texCache *TextureCache /* disabled texture cache shared by all jobs (see GPU::SubmitPrimitive) */
+
+	/* areas of vram drawn and textured from since the last sync */
+	written *VRAMArea
+	read    *VRAMArea
+}
+
+/* bounding box (inclusive) of vram accesses */
+type VRAMArea struct {
+	valid          bool
+	x1, y1, x2, y2 int
+}
+
+func (area *VRAMArea) Add(x1, y1, x2, y2 int) {
+	if !area.valid {
+		area.x1, area.y1, area.x2, area.y2 = x1, y1, x2, y2
+		area.valid = true
+		return
+	}
+
+	area.x1 = MinOf(area.x1, x1)
+	area.y1 = MinOf(area.y1, y1)
+	area.x2 = MaxOf(area.x2, x2)
+	area.y2 = MaxOf(area.y2, y2)
+}
+
+func (area *VRAMArea) Overlaps(x1, y1, x2, y2 int) bool {
+	return area.valid && Overlaps(x1, y1, x2, y2, area.x1, area.y1, area.x2, area.y2)
+}
+
+/* do the two (inclusive) rectangles intersect? */
+func Overlaps(ax1, ay1, ax2, ay2, bx1, by1, bx2, by2 int) bool {
+	return ax1 <= bx2 && ax2 >= bx1 && ay1 <= by2 && ay2 >= by1
+}
+
+func NewRenderThreads(n int) *RenderThreads {
+	threads := &RenderThreads{
+		n,
+		make([]chan *GPU, n),
+		sync.WaitGroup{},
+		NewTextureCache(false),
+		&VRAMArea{},
+		&VRAMArea{},
+	}
+
+	for i := 0; i < n; i += 1 {
+		threads.jobs[i] = make(chan *GPU, RENDER_THREAD_QUEUE_SIZE)
+		go threads.Run(i)
+	}
+
+	return threads
+}
+
+func (threads *RenderThreads) Run(i int) {
+	for snapshot := range threads.jobs[i] {
+		job := *snapshot
+		job.threads = nil
+		job.lineStep = threads.n
+		job.lineOffset = i
+
+		switch job.shape {
+		case PRIMITIVE_POLYGON:
+			job.ProcessPolygonCommand()
+		case PRIMITIVE_RECTANGLE:
+			job.ProcessRectangleCommand()
+		}
+
+		threads.wg.Done()
+	}
+}
+
+func (threads *RenderThreads) Submit(snapshot *GPU) {
+	threads.wg.Add(threads.n)
+
+	for i := 0; i < threads.n; i += 1 {
+		threads.jobs[i] <- snapshot
+	}
+}
+
+/* blocks until all submitted primitives are drawn */
+func (threads *RenderThreads) Sync() {
+	threads.wg.Wait()
+	threads.written.valid = false
+	threads.read.valid = false
+}
+
+/*
+Selects the renderer; n > 0 starts n render threads, otherwise primitives are drawn on the emulation goroutine
+*/
+func (gpu *GPU) StartRenderThreads(n int) {
+	if n <= 0 {
+		return
+	}
+
+	if _, ok := gpu.renderer.(*SoftwareRenderer); !ok {
+		fmt.Println("[GPU::StartRenderThreads] render threads only work with the reference renderer")
+		return
+	}
+
+	gpu.threads = NewRenderThreads(n)
+}
+
+/* call this before touching vram outside of the rasterizer */
+func (gpu *GPU) SyncRenderThreads() {
+	if gpu.threads != nil {
+		gpu.threads.Sync()
+	}
+}
+
+/* can this (render thread's copy of the) gpu draw line y? */
+func (gpu *GPU) OwnsLine(y int) bool {
+	return gpu.lineStep <= 1 || Modulo(y, gpu.lineStep) == gpu.lineOffset
+}
+
+/*
+Hands the primitive which was just received over to the render threads; returns false when it has to be drawn
+on the emulation goroutine instead because it goes thru the texture cache or textures itself from the area it
+draws to
+*/
+func (gpu *GPU) SubmitPrimitive() bool {
+	if gpu.PrimitiveIsTextured() && gpu.texCache.enabled {
+		// texel fetches have to hit the cache in the same order as in the single-threaded renderer
+		gpu.threads.Sync()
+		return false
+	}
+
+	x1, y1, x2, y2, pixels := gpu.PrimitiveBounds()
+
+	// clip to the drawing area since nothing outside of it gets drawn
+	x1 = MaxOf(x1, gpu.drawingAreaX1)
+	y1 = MaxOf(y1, gpu.drawingAreaY1)
+	x2 = MinOf(x2, gpu.drawingAreaX2)
+	y2 = MinOf(y2, gpu.drawingAreaY2)
+
+	if x1 <= x2 && y1 <= y2 && gpu.threads.read.Overlaps(x1, y1, x2, y2) {
+		// drawing over a texture which queued primitives still need
+		gpu.threads.Sync()
+	}
+
+	if gpu.PrimitiveIsTextured() {
+		tx1, ty1, tx2, ty2, clutX1, clutX2, clutY := gpu.PrimitiveTextureSource()
+
+		if x1 <= x2 && y1 <= y2 && (Overlaps(x1, y1, x2, y2, tx1, ty1, tx2, ty2) || Overlaps(x1, y1, x2, y2, clutX1, clutY, clutX2, clutY)) {
+			// the result depends on the order pixels are drawn in which only holds for a single thread
+			gpu.threads.Sync()
+			return false
+		}
+
+		if gpu.threads.written.Overlaps(tx1, ty1, tx2, ty2) || gpu.threads.written.Overlaps(clutX1, clutY, clutX2, clutY) {
+			// rendering to a texture; wait for it to be finished
+			gpu.threads.Sync()
+		}
+
+		gpu.threads.read.Add(tx1, ty1, tx2, ty2)
+		gpu.threads.read.Add(clutX1, clutY, clutX2, clutY)
+	}
+
+	snapshot := *gpu
+	fifo := *gpu.fifo
+	snapshot.fifo = &fifo
+	snapshot.texCache = gpu.threads.texCache
+
+	gpu.threads.Submit(&snapshot)
+
+	if x1 <= x2 && y1 <= y2 {
+		gpu.threads.written.Add(x1, y1, x2, y2)
+	}
+
+	// the threads draw asynchronously so the draw time has to be estimated from the primitive's size
+	gpu.pixelsDrawn = pixels
+	if TestBit(gpu.shape_attr, PATTR_SEMI_TRANSPARENT) {
+		gpu.pixelsBlended = pixels
+	}
+	if gpu.PrimitiveIsTextured() {
+		gpu.texCacheMisses = pixels / 8
+	}
+
+	return true
+}
+
+func (gpu *GPU) PrimitiveIsTextured() bool {
+	// bit 2 means textured for both polygons and rectangles
+	return TestBit(gpu.shape_attr, PATTR_TEXTURE)
+}
+
+/*
+Returns bounding box (inclusive) and approximate number of pixels of the primitive in the argument fifo
+*/
+func (gpu *GPU) PrimitiveBounds() (int, int, int, int, int) {
+	if gpu.shape == PRIMITIVE_RECTANGLE {
+		x := int(ForceSignExtension16(uint16(gpu.fifo.buffer[1]&0xffff), 11)) + gpu.drawingXOffset
+		y := int(ForceSignExtension16(uint16(gpu.fifo.buffer[1]>>16), 11)) + gpu.drawingYOffset
+
+		var w, h int
+		switch GetRange(gpu.shape_attr, 3, 2) {
+		case RSIZE_1x1:
+			w, h = 1, 1
+		case RSIZE_8x8:
+			w, h = 8, 8
+		case RSIZE_16x16:
+			w, h = 16, 16
+		default:
+			size := gpu.fifo.buffer[2]
+			if gpu.PrimitiveIsTextured() {
+				size = gpu.fifo.buffer[3]
+			}
+			w = MinOf(int(size&0xffff), VRAM_WIDTH-1)
+			h = MinOf(int(size>>16), VRAM_HEIGHT-1)
+		}
+
+		return x, y, x + w - 1, y + h - 1, w * h
+	}
+
+	// vertices are spread over the arguments; see GPU::ProcessPolygonCommand
+	stride := 1
+	if TestBit(gpu.shape_attr, PATTR_TEXTURE) {
+		stride += 1
+	}
+	if TestBit(gpu.shape_attr, PATTR_GOURAUD) {
+		stride += 1
+	}
+
+	nvert := 3
+	if TestBit(gpu.shape_attr, PATTR_QUAD) {
+		nvert = 4
+	}
+
+	xs := make([]int, nvert)
+	ys := make([]int, nvert)
+
+	for i := 0; i < nvert; i += 1 {
+		v := NewVertex(gpu.fifo.buffer[1+i*stride], 0, 0, gpu.drawingXOffset, gpu.drawingYOffset)
+		xs[i] = v.x
+		ys[i] = v.y
+	}
+
+	pixels := AbsOf(Edge(xs[0], ys[0], xs[1], ys[1], xs[2], ys[2])) / 2
+	if nvert == 4 {
+		pixels += AbsOf(Edge(xs[1], ys[1], xs[2], ys[2], xs[3], ys[3])) / 2
+	}
+
+	return MinOf(xs...), MinOf(ys...), MaxOf(xs...), MaxOf(ys...), pixels
+}
+
+/*
+Returns the area of vram (inclusive) which a textured primitive may read texels from and the span of its clut
+*/
+func (gpu *GPU) PrimitiveTextureSource() (int, int, int, int, int, int, int) {
+	var clutIndex, texPage uint32
+
+	if gpu.shape == PRIMITIVE_RECTANGLE {
+		// rectangles use the texpage from GP0(E1h)
+		clutIndex = gpu.fifo.buffer[2] >> 16
+		texPage = uint32(gpu.txBase) | uint32(gpu.tyBase)<<4 | uint32(gpu.textureFormat)<<7
+	} else {
+		uvIndex := 2
+		stride := 2
+		if TestBit(gpu.shape_attr, PATTR_GOURAUD) {
+			stride = 3
+		}
+		clutIndex = gpu.fifo.buffer[uvIndex] >> 16
+		texPage = gpu.fifo.buffer[uvIndex+stride] >> 16
+	}
+
+	clutX := int(GetRange(clutIndex, 0, 6) * 16)
+	clutY := int(GetRange(clutIndex, 6, 9))
+
+	x := int(GetRange(texPage, 0, 4) * 64)
+	y := int(GetRange(texPage, 4, 1) * 256)
+
+	// a texture page is 256x256 texels; the width in vram depends on the texture format
+	width := 256
+	switch GetRange(texPage, 7, 2) {
+	case TEXTURE_FORMAT_4b:
+		width = 64
+	case TEXTURE_FORMAT_8b:
+		width = 128
+	}
+
+	// pages and cluts wrap around the right edge of vram
+	x1, x2 := x, x+width-1
+	if x2 >= VRAM_WIDTH {
+		x1, x2 = 0, VRAM_WIDTH-1
+	}
+
+	clutX1, clutX2 := clutX, clutX+255
+	if clutX2 >= VRAM_WIDTH {
+		clutX1, clutX2 = 0, VRAM_WIDTH-1
+	}
+
+	return x1, y, x2, y + 255, clutX1, clutX2, clutY
+}
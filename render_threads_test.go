@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRenderThreadsMatchSingleThreaded(t *testing.T) {
+	stream := RandomGP0Stream(1, 3000)
+
+	for _, texCache := range []bool{true, false} {
+		reference := NewTestGPU()
+		reference.SetTextureCacheEnabled(texCache)
+		SendGP0(reference, stream...)
+
+		for _, n := range []int{2, 3, 4} {
+			t.Run(fmt.Sprintf("texture cache %t, %d threads", texCache, n), func(t *testing.T) {
+				gpu := NewTestGPU()
+				gpu.StartRenderThreads(n)
+				gpu.SetTextureCacheEnabled(texCache)
+				SendGP0(gpu, stream...)
+
+				CompareVRAM(t, gpu, reference)
+			})
+		}
+	}
+}
+
+/* same stream with and without the texture cache has to differ, otherwise the test above proves nothing */
+func TestRandomGP0StreamHitsStaleTexels(t *testing.T) {
+	stream := RandomGP0Stream(1, 3000)
+
+	cached := NewTestGPU()
+	cached.SetTextureCacheEnabled(true)
+	SendGP0(cached, stream...)
+
+	uncached := NewTestGPU()
+	uncached.SetTextureCacheEnabled(false)
+	SendGP0(uncached, stream...)
+
+	for i := range cached.vram.buffer {
+		if cached.vram.buffer[i] != uncached.vram.buffer[i] {
+			return
+		}
+	}
+
+	t.Error("the texture cache doesn't make a difference")
+}
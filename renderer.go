@@ -0,0 +1,289 @@
+package main
+
+import "fmt"
+
+/*
+Renderer backends
+
+The gpu decodes GP0 commands (see GPU::ProcessPolygonCommand, GPU::ProcessRectangleCommand, GPU::GP0FillVRam
+and GPU::GP0DoTransferVRAMToVRAM) and hands the primitives over to a renderer which does the actual drawing.
+Coordinates passed to a renderer are always in 1024x512 vram space with the drawing offset already applied;
+the rest of the drawing state (drawing area, texture window, mask settings...) is read from the gpu.
+
+gpu.vram is what the cpu sees. A renderer may draw somewhere else as long as:
+- UploadVRAM copies what the cpu wrote into gpu.vram to wherever the renderer draws
+- SyncVRAM writes everything drawn so far back into gpu.vram (before the cpu or the frontend reads it)
+*/
+type Renderer interface {
+	DrawTriangle(gpu *GPU, v1, v2, v3 *Vertex, stMode int, attr uint32)
+	DrawTexturedTriangle(gpu *GPU, v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32)
+	DrawRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b int, attr uint32)
+	DrawTexturedRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b, u, v, clutX, clutY int, attr uint32)
+	FillRectangle(gpu *GPU, x, y, width, height int, colour uint16)
+	CopyRectangle(gpu *GPU, srcX, srcY, dstX, dstY, width, height int)
+
+	UploadVRAM(vram *VRAM, x, y, width, height int)
+	SyncVRAM(vram *VRAM)
+
+	/* what the display gets scanned out from (scaled by Output().scale) */
+	Output(gpu *GPU) *VRAM
+}
+
+/*
+Reference backend; draws straight into gpu.vram
+*/
+type SoftwareRenderer struct{}
+
+func NewSoftwareRenderer() *SoftwareRenderer {
+	return &SoftwareRenderer{}
+}
+
+func (renderer *SoftwareRenderer) DrawTriangle(gpu *GPU, v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	gpu.RenderTriangle(v1, v2, v3, stMode, attr)
+}
+
+func (renderer *SoftwareRenderer) DrawTexturedTriangle(gpu *GPU, v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	gpu.RenderTexturedTriangle(v1, v2, v3, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode, attr)
+}
+
+func (renderer *SoftwareRenderer) DrawRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b int, attr uint32) {
+	gpu.RenderRectangle(x1, y1, x2, y2, r, g, b, attr)
+}
+
+func (renderer *SoftwareRenderer) DrawTexturedRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b, u, v, clutX, clutY int, attr uint32) {
+	gpu.RenderTexturedRectangle(x1, y1, x2, y2, r, g, b, u, v, clutX, clutY, attr)
+}
+
+func (renderer *SoftwareRenderer) FillRectangle(gpu *GPU, x, y, width, height int, colour uint16) {
+	gpu.FillVRAMRectangle(x, y, width, height, colour)
+}
+
+func (renderer *SoftwareRenderer) CopyRectangle(gpu *GPU, srcX, srcY, dstX, dstY, width, height int) {
+	gpu.CopyVRAMRectangle(srcX, srcY, dstX, dstY, width, height)
+}
+
+func (renderer *SoftwareRenderer) UploadVRAM(vram *VRAM, x, y, width, height int) {
+	// already there
+}
+
+func (renderer *SoftwareRenderer) SyncVRAM(vram *VRAM) {
+	// nothing to write back
+}
+
+func (renderer *SoftwareRenderer) Output(gpu *GPU) *VRAM {
+	return gpu.vram
+}
+
+/*
+Software backend which draws at scale times the native resolution into its own vram.
+
+Every primitive is drawn by a copy of the gpu (see UpscalingRenderer::Shadow) whose vram, drawing area and
+vertexes are scaled up; the rasterizer itself is the same as the reference one. Textures are sampled at
+native resolution (the top left sample of each texel) so cpu uploaded textures and cluts look the same
+as in the reference renderer.
+
+Areas which were drawn to are remembered and scaled back down into gpu.vram by SyncVRAM.
+*/
+type UpscalingRenderer struct {
+	scale    int
+	vram     *VRAM
+	texCache *TextureCache /* disabled; texel fetches go straight to the scaled vram */
+	dirty    *VRAMArea     /* in native coordinates */
+}
+
+func NewUpscalingRenderer(scale int, vram *VRAM) *UpscalingRenderer {
+	renderer := &UpscalingRenderer{
+		scale,
+		NewVRAM(scale),
+		NewTextureCache(false),
+		&VRAMArea{},
+	}
+
+	renderer.UploadVRAM(vram, 0, 0, VRAM_WIDTH, VRAM_HEIGHT)
+
+	return renderer
+}
+
+/*
+Returns a copy of the gpu which draws into the scaled vram
+*/
+func (renderer *UpscalingRenderer) Shadow(gpu *GPU) *GPU {
+	shadow := *gpu
+	scale := renderer.scale
+
+	shadow.vram = renderer.vram
+	shadow.texCache = renderer.texCache
+	shadow.threads = nil
+	shadow.lineStep = 1
+
+	// the drawing area is inclusive so the last line/column expands to scale lines/columns
+	shadow.drawingAreaX1 = gpu.drawingAreaX1 * scale
+	shadow.drawingAreaY1 = gpu.drawingAreaY1 * scale
+	shadow.drawingAreaX2 = (gpu.drawingAreaX2+1)*scale - 1
+	shadow.drawingAreaY2 = (gpu.drawingAreaY2+1)*scale - 1
+
+	shadow.pixelsDrawn = 0
+	shadow.pixelsBlended = 0
+	shadow.texCacheMisses = 0
+
+	return &shadow
+}
+
+/*
+Hands the draw statistics of a shadow back to the gpu (in native pixels) and remembers the drawn area
+*/
+func (renderer *UpscalingRenderer) Finish(gpu, shadow *GPU, x1, y1, x2, y2 int) {
+	samples := renderer.scale * renderer.scale
+
+	gpu.pixelsDrawn += shadow.pixelsDrawn / samples
+	gpu.pixelsBlended += shadow.pixelsBlended / samples
+	gpu.texCacheMisses += shadow.texCacheMisses / samples
+
+	x1 = MaxOf(x1, gpu.drawingAreaX1)
+	y1 = MaxOf(y1, gpu.drawingAreaY1)
+	x2 = MinOf(x2, gpu.drawingAreaX2)
+	y2 = MinOf(y2, gpu.drawingAreaY2)
+
+	if x1 <= x2 && y1 <= y2 {
+		renderer.dirty.Add(x1, y1, x2, y2)
+	}
+}
+
+func (renderer *UpscalingRenderer) ScaleVertex(v *Vertex) *Vertex {
+	precise := v.precise
+	if precise != nil {
+		s := float64(renderer.scale)
+		precise = &PreciseVertex{precise.x * s, precise.y * s, precise.w}
+	}
+
+	return &Vertex{
+		v.x * renderer.scale,
+		v.y * renderer.scale,
+		v.r,
+		v.g,
+		v.b,
+		v.u,
+		v.v,
+		precise,
+	}
+}
+
+func (renderer *UpscalingRenderer) DrawTriangle(gpu *GPU, v1, v2, v3 *Vertex, stMode int, attr uint32) {
+	shadow := renderer.Shadow(gpu)
+	shadow.RenderTriangle(renderer.ScaleVertex(v1), renderer.ScaleVertex(v2), renderer.ScaleVertex(v3), stMode, attr)
+
+	renderer.Finish(gpu, shadow, MinOf(v1.x, v2.x, v3.x), MinOf(v1.y, v2.y, v3.y), MaxOf(v1.x, v2.x, v3.x), MaxOf(v1.y, v2.y, v3.y))
+}
+
+func (renderer *UpscalingRenderer) DrawTexturedTriangle(gpu *GPU, v1, v2, v3 *Vertex, clutX, clutY, texPageUBase, texPageVBase, texFormat, stMode int, attr uint32) {
+	shadow := renderer.Shadow(gpu)
+	shadow.RenderTexturedTriangle(
+		renderer.ScaleVertex(v1),
+		renderer.ScaleVertex(v2),
+		renderer.ScaleVertex(v3),
+		clutX,
+		clutY,
+		texPageUBase,
+		texPageVBase,
+		texFormat,
+		stMode,
+		attr,
+	)
+
+	renderer.Finish(gpu, shadow, MinOf(v1.x, v2.x, v3.x), MinOf(v1.y, v2.y, v3.y), MaxOf(v1.x, v2.x, v3.x), MaxOf(v1.y, v2.y, v3.y))
+}
+
+func (renderer *UpscalingRenderer) DrawRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b int, attr uint32) {
+	s := renderer.scale
+
+	shadow := renderer.Shadow(gpu)
+	shadow.RenderRectangle(x1*s, y1*s, x2*s, y2*s, r, g, b, attr)
+
+	renderer.Finish(gpu, shadow, x1, y1, x2-1, y2-1)
+}
+
+func (renderer *UpscalingRenderer) DrawTexturedRectangle(gpu *GPU, x1, y1, x2, y2, r, g, b, u, v, clutX, clutY int, attr uint32) {
+	s := renderer.scale
+
+	shadow := renderer.Shadow(gpu)
+	shadow.RenderTexturedRectangle(x1*s, y1*s, x2*s, y2*s, r, g, b, u, v, clutX, clutY, attr)
+
+	renderer.Finish(gpu, shadow, x1, y1, x2-1, y2-1)
+}
+
+func (renderer *UpscalingRenderer) FillRectangle(gpu *GPU, x, y, width, height int, colour uint16) {
+	s := renderer.scale
+
+	renderer.Shadow(gpu).FillVRAMRectangle(x*s, y*s, width*s, height*s, colour)
+	renderer.MarkDirty(x, y, width, height)
+}
+
+func (renderer *UpscalingRenderer) CopyRectangle(gpu *GPU, srcX, srcY, dstX, dstY, width, height int) {
+	s := renderer.scale
+
+	renderer.Shadow(gpu).CopyVRAMRectangle(srcX*s, srcY*s, dstX*s, dstY*s, width*s, height*s)
+	renderer.MarkDirty(dstX, dstY, width, height)
+}
+
+/* marks a rectangle (which may wrap around the edges of vram) as drawn */
+func (renderer *UpscalingRenderer) MarkDirty(x, y, width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	if x+width > VRAM_WIDTH {
+		x, width = 0, VRAM_WIDTH
+	}
+
+	if y+height > VRAM_HEIGHT {
+		y, height = 0, VRAM_HEIGHT
+	}
+
+	renderer.dirty.Add(x, y, x+width-1, y+height-1)
+}
+
+func (renderer *UpscalingRenderer) UploadVRAM(vram *VRAM, x, y, width, height int) {
+	for row := 0; row < height; row += 1 {
+		for col := 0; col < width; col += 1 {
+			vx := Modulo(x+col, VRAM_WIDTH)
+			vy := Modulo(y+row, VRAM_HEIGHT)
+
+			renderer.vram.WriteNative16(vx, vy, vram.Read16(vx, vy))
+		}
+	}
+}
+
+func (renderer *UpscalingRenderer) SyncVRAM(vram *VRAM) {
+	if !renderer.dirty.valid {
+		return
+	}
+
+	area := renderer.dirty
+
+	for y := area.y1; y <= area.y2; y += 1 {
+		for x := area.x1; x <= area.x2; x += 1 {
+			vram.Write16(x, y, renderer.vram.ReadNative16(x, y))
+		}
+	}
+
+	area.valid = false
+}
+
+func (renderer *UpscalingRenderer) Output(gpu *GPU) *VRAM {
+	return renderer.vram
+}
+
+/*
+Selects the renderer backend; anything drawn by the previous one is written back into vram first
+*/
+func (gpu *GPU) SetRenderer(renderer Renderer) {
+	gpu.SyncRenderThreads()
+	gpu.renderer.SyncVRAM(gpu.vram)
+
+	if _, ok := renderer.(*SoftwareRenderer); !ok && gpu.threads != nil {
+		fmt.Println("[GPU::SetRenderer] render threads only work with the reference renderer; drawing on the emulation thread")
+		gpu.threads = nil
+	}
+
+	gpu.renderer = renderer
+}
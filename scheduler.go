@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+)
+
+/*
+Event scheduler
+
+Keeps the time of the console (cpu cycles since power on) and the events devices want to happen at a
+certain time: the gpu (next scanline or end of the command it is drawing), the timers (next target or
+overflow interrupt), the spu (next 44100Hz sample), the cdrom (command responses), dma (end of a
+transfer) and the end of each frame. The cpu runs until the next event is due, then the due events are
+run in order of their time (events with the same time in the order they were scheduled).
+
+Devices which only need to be up to date when they are accessed (like the gpu and the timers) catch up
+lazily and only use their event for things that have to happen on time, like interrupts.
+*/
+type Event struct {
+	Name     string
+	time     uint64
+	callback func()
+	pending  bool
+}
+
+type Scheduler struct {
+	now    uint64
+	next   uint64   /* time of the earliest pending event */
+	events []*Event /* pending events sorted by time */
+}
+
+func NewEvent(name string, callback func()) *Event {
+	return &Event{
+		name,
+		0,
+		callback,
+		false,
+	}
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		0,
+		math.MaxUint64,
+		nil,
+	}
+}
+
+func (scheduler *Scheduler) Now() uint64 {
+	return scheduler.now
+}
+
+func (scheduler *Scheduler) Advance(cycles uint32) {
+	scheduler.now += uint64(cycles)
+}
+
+/* whether an event has to be run before the cpu continues */
+func (scheduler *Scheduler) Due() bool {
+	return scheduler.now >= scheduler.next
+}
+
+/* cycles until the next event (0 if it is due) */
+func (scheduler *Scheduler) CyclesUntilNextEvent() uint64 {
+	if scheduler.Due() {
+		return 0
+	}
+
+	return scheduler.next - scheduler.now
+}
+
+/* (re)schedules an event to happen in the given number of cycles */
+func (scheduler *Scheduler) Schedule(event *Event, cycles uint64) {
+	scheduler.ScheduleAt(event, scheduler.now+cycles)
+}
+
+func (scheduler *Scheduler) ScheduleAt(event *Event, time uint64) {
+	if event.pending {
+		scheduler.Cancel(event)
+	}
+
+	event.time = time
+	event.pending = true
+
+	i := len(scheduler.events)
+	for i > 0 && scheduler.events[i-1].time > time {
+		i -= 1
+	}
+
+	scheduler.events = append(scheduler.events, nil)
+	copy(scheduler.events[i+1:], scheduler.events[i:])
+	scheduler.events[i] = event
+
+	scheduler.next = scheduler.events[0].time
+}
+
+func (scheduler *Scheduler) Cancel(event *Event) {
+	if !event.pending {
+		return
+	}
+
+	for i, pending := range scheduler.events {
+		if pending == event {
+			scheduler.events = append(scheduler.events[:i], scheduler.events[i+1:]...)
+			break
+		}
+	}
+	event.pending = false
+
+	scheduler.UpdateNext()
+}
+
+func (scheduler *Scheduler) UpdateNext() {
+	if len(scheduler.events) == 0 {
+		scheduler.next = math.MaxUint64
+	} else {
+		scheduler.next = scheduler.events[0].time
+	}
+}
+
+/* runs every event which is due; callbacks may schedule events again */
+func (scheduler *Scheduler) RunEvents() {
+	for len(scheduler.events) > 0 && scheduler.events[0].time <= scheduler.now {
+		event := scheduler.events[0]
+		scheduler.events = scheduler.events[1:]
+		event.pending = false
+		scheduler.UpdateNext()
+
+		event.callback()
+	}
+}
+
+/* time an event is going to happen at (only meaningful while it is pending) */
+func (event *Event) Time() uint64 {
+	return event.time
+}
+
+func (event *Event) Pending() bool {
+	return event.pending
+}
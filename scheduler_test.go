@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+/* scheduler with events a..e which append their name to log when they run */
+func NewTestScheduler() (*Scheduler, map[string]*Event, *[]string) {
+	scheduler := NewScheduler()
+	events := map[string]*Event{}
+	log := &[]string{}
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		name := name
+		events[name] = NewEvent(name, func() { *log = append(*log, name) })
+	}
+
+	return scheduler, events, log
+}
+
+func TestEventsRunInOrderOfTime(t *testing.T) {
+	scheduler, events, log := NewTestScheduler()
+
+	scheduler.Schedule(events["a"], 30)
+	scheduler.Schedule(events["b"], 10)
+	scheduler.Schedule(events["c"], 20)
+
+	if next := scheduler.CyclesUntilNextEvent(); next != 10 {
+		t.Errorf("next event in %d cycles, expected 10", next)
+	}
+
+	scheduler.Advance(25)
+	if !scheduler.Due() {
+		t.Error("no event is due")
+	}
+	scheduler.RunEvents()
+
+	if !reflect.DeepEqual(*log, []string{"b", "c"}) {
+		t.Errorf("ran %v, expected [b c]", *log)
+	}
+	if !events["a"].Pending() || events["b"].Pending() {
+		t.Error("pending is wrong")
+	}
+	if next := scheduler.CyclesUntilNextEvent(); next != 5 {
+		t.Errorf("next event in %d cycles, expected 5", next)
+	}
+}
+
+func TestEventsAtTheSameTimeRunInOrderOfScheduling(t *testing.T) {
+	scheduler, events, log := NewTestScheduler()
+
+	scheduler.Schedule(events["c"], 10)
+	scheduler.Schedule(events["a"], 10)
+	scheduler.Schedule(events["e"], 5)
+	scheduler.Schedule(events["b"], 10)
+
+	scheduler.Advance(10)
+	scheduler.RunEvents()
+
+	if !reflect.DeepEqual(*log, []string{"e", "c", "a", "b"}) {
+		t.Errorf("ran %v, expected [e c a b]", *log)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	scheduler, events, log := NewTestScheduler()
+
+	scheduler.Schedule(events["a"], 10)
+	scheduler.Schedule(events["b"], 20)
+	scheduler.Cancel(events["a"])
+	scheduler.Cancel(events["c"]) // not pending
+
+	if events["a"].Pending() {
+		t.Error("cancelled event is pending")
+	}
+	if next := scheduler.CyclesUntilNextEvent(); next != 20 {
+		t.Errorf("next event in %d cycles, expected 20", next)
+	}
+
+	scheduler.Advance(100)
+	scheduler.RunEvents()
+
+	if !reflect.DeepEqual(*log, []string{"b"}) {
+		t.Errorf("ran %v, expected [b]", *log)
+	}
+
+	scheduler.Cancel(events["b"])
+	if scheduler.Due() {
+		t.Error("due without events")
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	scheduler, events, log := NewTestScheduler()
+
+	scheduler.Schedule(events["a"], 10)
+	scheduler.Schedule(events["b"], 20)
+
+	// later, then earlier again: an event is only pending once
+	scheduler.Schedule(events["a"], 30)
+	scheduler.Schedule(events["b"], 5)
+
+	scheduler.Advance(30)
+	scheduler.RunEvents()
+
+	if !reflect.DeepEqual(*log, []string{"b", "a"}) {
+		t.Errorf("ran %v, expected [b a]", *log)
+	}
+}
+
+/* callbacks which schedule events again: one that is due already runs in the same RunEvents */
+func TestEventsScheduledByCallbacks(t *testing.T) {
+	scheduler := NewScheduler()
+	times := []uint64{}
+
+	var tick *Event
+	tick = NewEvent("tick", func() {
+		times = append(times, tick.Time())
+		if len(times) < 4 {
+			scheduler.ScheduleAt(tick, tick.Time()+10)
+		}
+	})
+	scheduler.Schedule(tick, 10)
+
+	scheduler.Advance(25)
+	scheduler.RunEvents()
+
+	if !reflect.DeepEqual(times, []uint64{10, 20}) {
+		t.Errorf("ran at %v, expected [10 20]", times)
+	}
+	if tick.Time() != 30 || !tick.Pending() {
+		t.Errorf("next tick at %d (pending %t), expected 30", tick.Time(), tick.Pending())
+	}
+}
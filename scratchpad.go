@@ -0,0 +1,33 @@
+package main
+
+/*
+https://psx-spx.consoledev.net/memorymap/#scratchpad
+
+The scratchpad is the data cache of the cpu used as 1KiB of fast ram. It isn't on the bus, so only the
+cpu can access it and only thru KUSEG and KSEG0 (1F800000h and 9F800000h) while both scratchpad enable
+bits of the cache control register are set. Accesses thru KSEG1 (BF800000h) or while it is disabled go
+to the bus where nothing answers and raise a bus error; code can't be fetched from it either. DMA can't
+reach it since dma addresses are masked to main ram.
+
+Scratchpad accesses don't take any bus cycles.
+*/
+const (
+	SCRATCHPAD_OFFSET = 0x1f800000
+	SCRATCHPAD_SIZE   = 0x400
+)
+
+func (icache *ICache) ScratchPadEnabled() bool {
+	return TestBit(icache.control, 3) && TestBit(icache.control, 7)
+}
+
+/* physical address of a (virtual) address if it goes to the scratchpad */
+func (cpu *CPU) ScratchPadAddress(address uint32) (uint32, bool) {
+	segment := address >> 29
+	physical := address & CPUAddressMask(segment)
+
+	if physical-SCRATCHPAD_OFFSET >= SCRATCHPAD_SIZE || segment > 4 || !cpu.icache.ScratchPadEnabled() {
+		return 0, false
+	}
+
+	return physical, true
+}
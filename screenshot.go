@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func WritePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+/*
+Saves what the console shows right now as png; either the display area (cropped to the display
+resolution, deinterlaced like display does it) or the whole 1024x512 vram
+*/
+func (gostation *GoStation) Screenshot(display *DisplayOutput, path string, wholeVRAM bool) error {
+	gpu := gostation.GPU
+
+	gpu.SyncRenderThreads()
+	gpu.renderer.SyncVRAM(gpu.vram)
+
+	if wholeVRAM {
+		return WritePNG(path, gpu.vram.Image())
+	}
+
+	return WritePNG(path, display.Render(gpu))
+}
+
+/* file name for a screenshot/video taken now, eg. gostation_20240131_235959.123.png */
+func CaptureName(dir string, extension string) string {
+	return filepath.Join(dir, fmt.Sprintf("gostation_%s.%s", time.Now().Format("20060102_150405.000"), extension))
+}
+
+/*
+Runs the emulator for a number of frames without a window; every dumpEvery-th frame is saved as png into
+dumpDir (if set) so the output can be compared between versions
+*/
+func (gostation *GoStation) RunHeadless(frames int, dumpDir string, dumpEvery int, wholeVRAM bool) error {
+	if dumpDir != "" {
+		if err := os.MkdirAll(dumpDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	if dumpEvery < 1 {
+		dumpEvery = 1
+	}
+
+	display := NewDisplayOutput(DEINTERLACE_WEAVE)
+
+	for frame := 0; frame < frames; frame += 1 {
+		gostation.Update()
+
+		if dumpDir == "" || frame%dumpEvery != 0 {
+			continue
+		}
+
+		path := filepath.Join(dumpDir, fmt.Sprintf("frame_%05d.png", frame))
+		if err := gostation.Screenshot(display, path, wholeVRAM); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
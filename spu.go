@@ -0,0 +1,57 @@
+package main
+
+/* cpu cycles per sample (the cpu clock is 44100Hz*300h) */
+const SPU_CYCLES_PER_SAMPLE = CPU_CYCLES_PER_SEC / 44100
+
+/*
+Sound processing unit
+
+Only its sample clock for now: an event ticks at 44100Hz and each tick produces a (for now silent) stereo
+sample, which is where voices, reverb and the cdrom audio are going to be mixed. The registers are still
+plain memory (see Bus::SPU).
+*/
+type SPU struct {
+	Core *GoStation
+
+	samples []int16 /* interleaved stereo samples produced since the last SPU::TakeSamples */
+	ticks   uint64  /* samples produced since power on */
+
+	event *Event /* next sample */
+}
+
+func NewSPU(core *GoStation) *SPU {
+	spu := &SPU{
+		core,
+		make([]int16, 0, 2*1024),
+		0,
+		nil,
+	}
+
+	spu.event = NewEvent("spu sample", spu.Tick)
+	core.Scheduler.Schedule(spu.event, SPU_CYCLES_PER_SAMPLE)
+
+	return spu
+}
+
+func (spu *SPU) Tick() {
+	// TODO mix the voices
+	var left, right int16 = 0, 0
+
+	if len(spu.samples) >= 2*44100 {
+		// nobody took them for a second (the console isn't run frame by frame)
+		spu.samples = spu.samples[:0]
+	}
+	spu.samples = append(spu.samples, left, right)
+	spu.ticks += 1
+
+	spu.Core.Scheduler.ScheduleAt(spu.event, spu.event.Time()+SPU_CYCLES_PER_SAMPLE)
+}
+
+/* returns the samples produced since the last call (eg. for a frame of video) */
+func (spu *SPU) TakeSamples() []int16 {
+	samples := make([]int16, len(spu.samples))
+	copy(samples, spu.samples)
+	spu.samples = spu.samples[:0]
+
+	return samples
+}
@@ -0,0 +1,86 @@
+package main
+
+const (
+	TEXCACHE_ENTRIES       = 256 /* 256 cache lines * 8 bytes = 2KiB */
+	TEXCACHE_LINE_HALFWORD = 4   /* each cache line holds 8 bytes (4 halfwords) of vram */
+)
+
+/*
+https://psx-spx.consoledev.net/graphicsprocessingunitgpu/#texture-cache
+
+The gpu has a 2KiB texture cache which is organized in 256 cache lines of 8 bytes each.
+Depending on the texture format, the cache covers an area of the texture page like this:
+
+	4bit  64x64 texels (16 texels per line, 4 lines per row, 64 rows)
+	8bit  32x64 texels ( 8 texels per line, 4 lines per row, 64 rows)
+	15bit 32x32 texels ( 4 texels per line, 8 lines per row, 32 rows)
+
+The cache is NOT updated when vram gets written (by rendering or by cpu/dma transfers); that's why
+games are supposed to send GP0(01h) after uploading textures. Games which forget to do that will
+see stale texels on real hardware.
+
+Emulating the cache is opt-in (-texture-cache): without it texels are read straight from vram and the
+misses are approximated for the timing (see GPU::TrackTextureFetch).
+*/
+type TextureCache struct {
+	enabled bool
+
+	valid [TEXCACHE_ENTRIES]bool
+	tagX  [TEXCACHE_ENTRIES]int /* vram x coordinate of the first halfword in the line */
+	tagY  [TEXCACHE_ENTRIES]int /* vram y coordinate of the line */
+	data  [TEXCACHE_ENTRIES][TEXCACHE_LINE_HALFWORD]uint16
+}
+
+func NewTextureCache(enabled bool) *TextureCache {
+	return &TextureCache{
+		enabled,
+		[TEXCACHE_ENTRIES]bool{},
+		[TEXCACHE_ENTRIES]int{},
+		[TEXCACHE_ENTRIES]int{},
+		[TEXCACHE_ENTRIES][TEXCACHE_LINE_HALFWORD]uint16{},
+	}
+}
+
+/* GP0(01h) */
+func (cache *TextureCache) Flush() {
+	cache.valid = [TEXCACHE_ENTRIES]bool{}
+}
+
+/* which cache line holds texel (u,v)? */
+func TextureCacheIndex(u, v, texFormat int) int {
+	switch texFormat {
+	case TEXTURE_FORMAT_4b:
+		return ((v & 0x3f) << 2) | ((u >> 4) & 0x3)
+	case TEXTURE_FORMAT_8b:
+		return ((v & 0x3f) << 2) | ((u >> 3) & 0x3)
+	default:
+		return ((v & 0x1f) << 3) | ((u >> 2) & 0x7)
+	}
+}
+
+/*
+Reads the halfword at vram (x,y) thru cache line index; returns false as second value on cache miss
+*/
+func (cache *TextureCache) Read(vram *VRAM, x, y, index int) (uint16, bool) {
+	lineX := x &^ (TEXCACHE_LINE_HALFWORD - 1)
+	hit := cache.valid[index] && cache.tagX[index] == lineX && cache.tagY[index] == y
+
+	if !hit {
+		// fill the whole line
+		for i := 0; i < TEXCACHE_LINE_HALFWORD; i += 1 {
+			cache.data[index][i] = vram.Read16(lineX+i, y)
+		}
+
+		cache.valid[index] = true
+		cache.tagX[index] = lineX
+		cache.tagY[index] = y
+	}
+
+	return cache.data[index][x-lineX], hit
+}
+
+/* texture cache emulation can be turned off for speed; texels are read straight from vram then */
+func (gpu *GPU) SetTextureCacheEnabled(enabled bool) {
+	gpu.texCache.enabled = enabled
+	gpu.texCache.Flush()
+}
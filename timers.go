@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	TIMERS_OFFSET = 0x1f801100
+	TIMERS_SIZE   = 3 * 16
+)
+
+/*
+	https://psx-spx.consoledev.net/timers/
+
+1F801104h+N*10h - Timer 0..2 Counter Mode (R/W)
+
+0     Synchronization Enable (0=Free Run, 1=Synchronize via Bit1-2)
+1-2   Synchronization Mode   (0-3, see lists below)
+3     Reset counter to 0000h  (0=After Counter=FFFFh, 1=After Counter=Target)
+4     IRQ when Counter=Target (0=Disable, 1=Enable)
+5     IRQ when Counter=FFFFh  (0=Disable, 1=Enable)
+6     IRQ Once/Repeat Mode    (0=One-shot, 1=Repeatedly)
+7     IRQ Pulse/Toggle Mode   (0=Short Bit10=0 Pulse, 1=Toggle Bit10 on/off)
+8-9   Clock Source (0-3, see list below)
+10    Interrupt Request       (0=Yes, 1=No) (Set after Writing)    (W=1) (R)
+11    Reached Target Value    (0=No, 1=Yes) (Reset after Reading)        (R)
+12    Reached FFFFh Value     (0=No, 1=Yes) (Reset after Reading)        (R)
+13-15 Unknown (seems to be always zero)
+*/
+const (
+	TMODE_SYNC_ENABLE     = 0
+	TMODE_RESET_AT_TARGET = 3
+	TMODE_IRQ_AT_TARGET   = 4
+	TMODE_IRQ_AT_FFFF     = 5
+	TMODE_IRQ_REPEAT      = 6
+	TMODE_IRQ_TOGGLE      = 7
+	TMODE_IRQ_REQUEST     = 10
+	TMODE_REACHED_TARGET  = 11
+	TMODE_REACHED_FFFF    = 12
+)
+
+/*
+Root counters
+
+Counters are brought up to date lazily (when they are accessed) from the time of the scheduler; each
+timer only has an event at the time it is going to request its next interrupt.
+
+Time is counted in 1/11 cpu cycles (1/7 video cycles) like the gpu does so every clock source has a
+whole number period:
+
+	Counter 0:  System Clock (11)  or Dotclock (dot divider*7)
+	Counter 1:  System Clock (11)  or Hblank (cycles per scanline*7)
+	Counter 2:  System Clock (11)  or System Clock/8 (88)
+
+Dotclock and hblank tick at their average rate (which isn't in phase with the gpu's actual hblanks) and
+the synchronization modes of counter 0 and 1 (pausing/resetting at h/vblank) aren't emulated; they run
+freely. Counter 2 is stopped in its synchronization modes 0 and 3.
+*/
+type Timer struct {
+	Core  *GoStation
+	index int
+
+	counter uint32 /* 1F801100h+N*10h */
+	mode    uint32 /* 1F801104h+N*10h */
+	target  uint32 /* 1F801108h+N*10h */
+
+	syncedAt uint64 /* scheduler time the counter is up to date with */
+	phase    uint32 /* 1/11 cycles since the last tick */
+	irqDone  bool   /* one-shot mode: the interrupt was requested since the mode was written */
+
+	event *Event /* next interrupt */
+}
+
+type Timers struct {
+	timer [3]*Timer
+}
+
+func NewTimers(core *GoStation) *Timers {
+	timers := &Timers{}
+
+	for i := range timers.timer {
+		timer := &Timer{
+			core,
+			i,
+			0,
+			1 << TMODE_IRQ_REQUEST,
+			0,
+			0,
+			0,
+			false,
+			nil,
+		}
+		timer.event = NewEvent(fmt.Sprintf("timer%d", i), timer.Sync)
+		timers.timer[i] = timer
+	}
+
+	return timers
+}
+
+func (timers *Timers) Contains(address uint32) bool {
+	return address >= TIMERS_OFFSET && address < (TIMERS_OFFSET+TIMERS_SIZE)
+}
+
+func (timers *Timers) Read16(address uint32) uint16 {
+	return uint16(timers.Read32(address))
+}
+
+func (timers *Timers) Read32(address uint32) uint32 {
+	timer := timers.timer[(address-TIMERS_OFFSET)/16]
+	timer.Sync()
+
+	switch address & 0xf {
+	case 0x0:
+		return timer.counter
+	case 0x4:
+		mode := timer.mode
+		timer.mode &^= 1<<TMODE_REACHED_TARGET | 1<<TMODE_REACHED_FFFF
+		return mode
+	case 0x8:
+		return timer.target
+	default:
+		return 0
+	}
+}
+
+func (timers *Timers) Write16(address uint32, data uint16) {
+	timers.Write32(address, uint32(data))
+}
+
+func (timers *Timers) Write32(address uint32, data uint32) {
+	timer := timers.timer[(address-TIMERS_OFFSET)/16]
+	timer.Sync()
+
+	switch address & 0xf {
+	case 0x0:
+		timer.counter = data & 0xffff
+	case 0x4:
+		// writing the mode resets the counter and the interrupt
+		timer.mode = data&0x3ff | timer.mode&(1<<TMODE_REACHED_TARGET|1<<TMODE_REACHED_FFFF) | 1<<TMODE_IRQ_REQUEST
+		timer.counter = 0
+		timer.phase = 0
+		timer.irqDone = false
+	case 0x8:
+		timer.target = data & 0xffff
+	}
+
+	timer.ScheduleEvent()
+}
+
+/* length of a tick of the counter's clock source in 1/11 cpu cycles; 0 if the counter is stopped */
+func (timer *Timer) Period() uint32 {
+	source := GetRange(timer.mode, 8, 2)
+
+	switch timer.index {
+	case 0:
+		if source&1 != 0 {
+			return timer.Core.GPU.DotDivider() * 7
+		}
+	case 1:
+		if source&1 != 0 {
+			if timer.Core.GPU.PALMode {
+				return VCYCLES_PER_SCANLINE_PAL * 7
+			}
+			return VCYCLES_PER_SCANLINE_NTSC * 7
+		}
+	case 2:
+		syncMode := GetRange(timer.mode, 1, 2)
+		if TestBit(timer.mode, TMODE_SYNC_ENABLE) && (syncMode == 0 || syncMode == 3) {
+			return 0
+		}
+		if source >= 2 {
+			return 8 * 11
+		}
+	}
+
+	return 11
+}
+
+/* the counter wraps to 0 after this value (a counter set past its target runs up to FFFFh first) */
+func (timer *Timer) WrapsAt() uint32 {
+	if TestBit(timer.mode, TMODE_RESET_AT_TARGET) && timer.counter <= timer.target {
+		return timer.target
+	}
+
+	return 0xffff
+}
+
+/* ticks until the counter reaches the target (again) */
+func (timer *Timer) TicksUntilTarget() uint32 {
+	if timer.counter < timer.target {
+		return timer.target - timer.counter
+	}
+
+	return timer.WrapsAt() - timer.counter + 1 + timer.target
+}
+
+/* ticks until the counter wraps to 0 */
+func (timer *Timer) TicksUntilWrap() uint32 {
+	return timer.WrapsAt() - timer.counter + 1
+}
+
+/* brings the counter up to date with the scheduler, requesting the interrupts it would have on the way */
+func (timer *Timer) Sync() {
+	now := timer.Core.Scheduler.Now()
+
+	if now > timer.syncedAt {
+		period := timer.Period()
+
+		if period == 0 {
+			// stopped
+			timer.syncedAt = now
+		} else {
+			elapsed := (now-timer.syncedAt)*11 + uint64(timer.phase)
+			timer.syncedAt = now
+			timer.phase = uint32(elapsed % uint64(period))
+
+			timer.Advance(elapsed / uint64(period))
+		}
+	}
+
+	timer.ScheduleEvent()
+}
+
+func (timer *Timer) Advance(ticks uint64) {
+	for ticks > 0 {
+		step := timer.TicksUntilTarget()
+		if wrap := timer.TicksUntilWrap(); wrap < step {
+			step = wrap
+		}
+		if ticks < uint64(step) {
+			timer.counter += uint32(ticks)
+			return
+		}
+
+		wrapsAt := timer.WrapsAt()
+		ticks -= uint64(step)
+		timer.counter += step
+
+		if timer.counter > wrapsAt {
+			if timer.counter > 0xffff {
+				timer.mode |= 1 << TMODE_REACHED_FFFF
+				if TestBit(timer.mode, TMODE_IRQ_AT_FFFF) {
+					timer.RequestIRQ()
+				}
+			}
+			timer.counter = 0
+		}
+
+		if timer.counter == timer.target {
+			timer.mode |= 1 << TMODE_REACHED_TARGET
+			if TestBit(timer.mode, TMODE_IRQ_AT_TARGET) {
+				timer.RequestIRQ()
+			}
+		}
+	}
+}
+
+/* IRQ4..IRQ6; bit 10 of the mode goes low for a moment (or toggles) */
+func (timer *Timer) RequestIRQ() {
+	if timer.irqDone && !TestBit(timer.mode, TMODE_IRQ_REPEAT) {
+		return
+	}
+	timer.irqDone = true
+
+	if TestBit(timer.mode, TMODE_IRQ_TOGGLE) {
+		timer.mode ^= 1 << TMODE_IRQ_REQUEST
+		if TestBit(timer.mode, TMODE_IRQ_REQUEST) {
+			return
+		}
+	}
+
+	timer.Core.Interrupts.Request(IRQ_TIMER0 + timer.index)
+}
+
+/* schedules the event at the time of the next interrupt (if there is going to be one) */
+func (timer *Timer) ScheduleEvent() {
+	timer.Core.Scheduler.Cancel(timer.event)
+
+	period := timer.Period()
+	if period == 0 || (timer.irqDone && !TestBit(timer.mode, TMODE_IRQ_REPEAT)) {
+		return
+	}
+
+	var ticks uint32 = 0
+	if TestBit(timer.mode, TMODE_IRQ_AT_TARGET) {
+		ticks = timer.TicksUntilTarget()
+	}
+	if TestBit(timer.mode, TMODE_IRQ_AT_FFFF) && timer.WrapsAt() == 0xffff {
+		if wrap := timer.TicksUntilWrap(); ticks == 0 || wrap < ticks {
+			ticks = wrap
+		}
+	}
+	if ticks == 0 {
+		return
+	}
+
+	units := uint64(ticks)*uint64(period) - uint64(timer.phase)
+	timer.Core.Scheduler.ScheduleAt(timer.event, timer.syncedAt+(units+10)/11) // round up
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"testing"
+)
+
+/* timer n of a console which isn't running; time is moved by PassTime */
+func NewTestTimer(t *testing.T, n int, mode, target uint32) (*GoStation, uint32) {
+	gostation := NewTestGoStation(t)
+	base := TIMERS_OFFSET + uint32(n)*16
+
+	gostation.Timers.Write32(base+8, target)
+	gostation.Timers.Write32(base+4, mode)
+
+	return gostation, base
+}
+
+/* advances the console's time and runs the events which became due */
+func PassTime(gostation *GoStation, cycles uint64) {
+	gostation.Scheduler.Advance(uint32(cycles))
+	gostation.Scheduler.RunEvents()
+}
+
+/* takes the timer's interrupt request out of I_STAT; returns whether it was there */
+func TakeTimerIRQ(gostation *GoStation, n int) bool {
+	irq := TestBit(gostation.Interrupts.Status, IRQ_TIMER0+n)
+	ModifyBit(&gostation.Interrupts.Status, IRQ_TIMER0+n, false)
+
+	return irq
+}
+
+func TestTimerTargetIRQ(t *testing.T) {
+	mode := uint32(1<<TMODE_RESET_AT_TARGET | 1<<TMODE_IRQ_AT_TARGET | 1<<TMODE_IRQ_REPEAT)
+	gostation, base := NewTestTimer(t, 0, mode, 100)
+
+	PassTime(gostation, 99)
+	if TakeTimerIRQ(gostation, 0) {
+		t.Fatal("irq before the target")
+	}
+
+	PassTime(gostation, 1)
+	if !TakeTimerIRQ(gostation, 0) {
+		t.Fatal("no irq at the target")
+	}
+	if counter := gostation.Timers.Read32(base); counter != 100 {
+		t.Errorf("counter is %d, expected 100", counter)
+	}
+
+	// 0..100 is 101 ticks
+	PassTime(gostation, 1)
+	if counter := gostation.Timers.Read32(base); counter != 0 {
+		t.Errorf("counter is %d after the target, expected 0", counter)
+	}
+	PassTime(gostation, 99)
+	if TakeTimerIRQ(gostation, 0) {
+		t.Fatal("second irq too early")
+	}
+	PassTime(gostation, 1)
+	if !TakeTimerIRQ(gostation, 0) {
+		t.Fatal("no second irq")
+	}
+
+	status := gostation.Timers.Read32(base + 4)
+	if !TestBit(status, TMODE_REACHED_TARGET) || !TestBit(status, TMODE_IRQ_REQUEST) {
+		t.Errorf("mode is %04x, expected reached target and no irq request in bit 10", status)
+	}
+	if status := gostation.Timers.Read32(base + 4); TestBit(status, TMODE_REACHED_TARGET) {
+		t.Error("reached target isn't reset by reading the mode")
+	}
+}
+
+func TestTimerOneShot(t *testing.T) {
+	gostation, _ := NewTestTimer(t, 1, 1<<TMODE_RESET_AT_TARGET|1<<TMODE_IRQ_AT_TARGET, 10)
+
+	PassTime(gostation, 10)
+	if !TakeTimerIRQ(gostation, 1) {
+		t.Fatal("no irq at the target")
+	}
+
+	PassTime(gostation, 100)
+	if TakeTimerIRQ(gostation, 1) {
+		t.Error("one-shot timer requested another irq")
+	}
+	if gostation.Timers.timer[1].event.Pending() {
+		t.Error("one-shot timer is still scheduled")
+	}
+}
+
+func TestTimerToggle(t *testing.T) {
+	mode := uint32(1<<TMODE_RESET_AT_TARGET | 1<<TMODE_IRQ_AT_TARGET | 1<<TMODE_IRQ_REPEAT | 1<<TMODE_IRQ_TOGGLE)
+	gostation, _ := NewTestTimer(t, 0, mode, 9)
+
+	// bit 10 goes low (irq) at the first target and high again at the second
+	irqs := []bool{}
+	for i := 0; i < 4; i += 1 {
+		PassTime(gostation, 10)
+		irqs = append(irqs, TakeTimerIRQ(gostation, 0))
+	}
+
+	if irqs[0] != true || irqs[1] != false || irqs[2] != true || irqs[3] != false {
+		t.Errorf("irqs are %v, expected every other target", irqs)
+	}
+}
+
+func TestTimerOverflow(t *testing.T) {
+	// system clock/8
+	gostation, base := NewTestTimer(t, 2, 1<<TMODE_IRQ_AT_FFFF|2<<8, 0x8000)
+
+	PassTime(gostation, 8*1000+7)
+	if counter := gostation.Timers.Read32(base); counter != 1000 {
+		t.Errorf("counter is %d, expected 1000", counter)
+	}
+
+	PassTime(gostation, 8*0x10000-8*1000-8)
+	if TakeTimerIRQ(gostation, 2) {
+		t.Fatal("irq before the overflow")
+	}
+	PassTime(gostation, 1)
+	if !TakeTimerIRQ(gostation, 2) {
+		t.Fatal("no irq at the overflow")
+	}
+
+	status := gostation.Timers.Read32(base + 4)
+	if !TestBit(status, TMODE_REACHED_FFFF) || !TestBit(status, TMODE_REACHED_TARGET) {
+		t.Errorf("mode is %04x, expected reached target and ffff", status)
+	}
+	if counter := gostation.Timers.Read32(base); counter != 0 {
+		t.Errorf("counter is %d after the overflow, expected 0", counter)
+	}
+}
+
+func TestTimerStopped(t *testing.T) {
+	// timer 2 in synchronization mode 0 doesn't count
+	gostation, base := NewTestTimer(t, 2, 1<<TMODE_SYNC_ENABLE|1<<TMODE_IRQ_AT_TARGET, 10)
+
+	PassTime(gostation, 1000)
+	if counter := gostation.Timers.Read32(base); counter != 0 {
+		t.Errorf("counter is %d, expected it to be stopped", counter)
+	}
+	if TakeTimerIRQ(gostation, 2) {
+		t.Error("stopped timer requested an irq")
+	}
+
+	// free run again
+	gostation.Timers.Write32(base+4, 1<<TMODE_IRQ_AT_TARGET)
+	PassTime(gostation, 10)
+	if !TakeTimerIRQ(gostation, 2) {
+		t.Error("no irq after starting the timer")
+	}
+}
+
+func TestTimerHblank(t *testing.T) {
+	gostation, base := NewTestTimer(t, 1, 1<<8, 0)
+
+	// a scanline is 3413 video cycles (3413*7/11 cpu cycles) in NTSC mode
+	PassTime(gostation, 3413*7*10/11+1)
+	if counter := gostation.Timers.Read32(base); counter != 10 {
+		t.Errorf("counter is %d after 10 scanlines, expected 10", counter)
+	}
+}
+
+func TestTimerCounterWrite(t *testing.T) {
+	gostation, base := NewTestTimer(t, 0, 1<<TMODE_RESET_AT_TARGET|1<<TMODE_IRQ_AT_FFFF, 100)
+
+	// past the target it runs up to ffffh before it wraps
+	gostation.Timers.Write32(base, 0xfff0)
+	PassTime(gostation, 0x10)
+	if !TakeTimerIRQ(gostation, 0) {
+		t.Fatal("no irq at the overflow")
+	}
+	if counter := gostation.Timers.Read32(base); counter != 0 {
+		t.Errorf("counter is %d after the overflow, expected 0", counter)
+	}
+}
+
+func TestSPUSampleClock(t *testing.T) {
+	gostation := NewTestGoStation(t)
+	gostation.SPU.TakeSamples()
+
+	// 44100Hz is every 768 cycles; 735 samples (of 2 channels) per 60Hz frame
+	PassTime(gostation, CPU_CYCLES_PER_SEC/60)
+	if samples := gostation.SPU.TakeSamples(); len(samples) != 2*735 {
+		t.Errorf("%d samples in a frame, expected %d", len(samples), 2*735)
+	}
+
+	PassTime(gostation, 767)
+	if samples := gostation.SPU.TakeSamples(); len(samples) != 0 {
+		t.Errorf("%d samples before the next one is due", len(samples))
+	}
+	PassTime(gostation, 1)
+	if samples := gostation.SPU.TakeSamples(); len(samples) != 2 {
+		t.Errorf("%d samples, expected a stereo sample", len(samples))
+	}
+}
@@ -1,33 +1,110 @@
-package main
-
-const (
-	VRAM_WIDTH  = 1024
-	VRAM_HEIGHT = 512
-	VRAM_SIZE   = VRAM_WIDTH * VRAM_HEIGHT
-)
-
-/*
-vram is an array of 16 bit pixels; it can be treated as a framebuffer of size 1024x512 for development purposes
-pixel format for 15bit direct display:
-
-pixel|                                               |
-bit  |0f|0e 0d 0c 0b 0a|09 08 07 06 05|04 03 02 01 00|
-desc.|M |Blue          |Green         |Red           |
-*/
-type VRAM struct {
-	buffer [VRAM_SIZE]uint16
-}
-
-func NewVRAM() *VRAM {
-	return &VRAM{
-		[VRAM_SIZE]uint16{},
-	}
-}
-
-func (vram *VRAM) Read16(x int, y int) uint16 {
-	return vram.buffer[y*VRAM_WIDTH+x]
-}
-
-func (vram *VRAM) Write16(x int, y int, data uint16) {
-	vram.buffer[y*VRAM_WIDTH+x] = data
-}
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	VRAM_WIDTH  = 1024
+	VRAM_HEIGHT = 512
+	VRAM_SIZE   = VRAM_WIDTH * VRAM_HEIGHT
+)
+
+/*
+vram is an array of 16 bit pixels; it can be treated as a framebuffer of size 1024x512 for development purposes
+pixel format for 15bit direct display:
+
+pixel|                                               |
+bit  |0f|0e 0d 0c 0b 0a|09 08 07 06 05|04 03 02 01 00|
+desc.|M |Blue          |Green         |Red           |
+*/
+type VRAM struct {
+	buffer []uint16
+
+	/* renderers drawing at a higher internal resolution use a vram which is scale times larger in each dimension */
+	scale  int
+	width  int
+	height int
+}
+
+func NewVRAM(scale int) *VRAM {
+	return &VRAM{
+		make([]uint16, VRAM_SIZE*scale*scale),
+		scale,
+		VRAM_WIDTH * scale,
+		VRAM_HEIGHT * scale,
+	}
+}
+
+func (vram *VRAM) Read16(x int, y int) uint16 {
+	return vram.buffer[y*vram.width+x]
+}
+
+func (vram *VRAM) Write16(x int, y int, data uint16) {
+	vram.buffer[y*vram.width+x] = data
+}
+
+/* reads the pixel at (x, y) in 1024x512 coordinates (ie. the top left sample of the pixel in a scaled vram) */
+func (vram *VRAM) ReadNative16(x int, y int) uint16 {
+	return vram.buffer[y*vram.scale*vram.width+x*vram.scale]
+}
+
+/* writes all samples of the pixel at (x, y) in 1024x512 coordinates */
+func (vram *VRAM) WriteNative16(x int, y int, data uint16) {
+	for sy := y * vram.scale; sy < (y+1)*vram.scale; sy += 1 {
+		for sx := x * vram.scale; sx < (x+1)*vram.scale; sx += 1 {
+			vram.buffer[sy*vram.width+sx] = data
+		}
+	}
+}
+
+/* returns the whole vram as a 15bit image (at the resolution of the vram, ie. scaled if it is) */
+func (vram *VRAM) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, vram.width, vram.height))
+
+	for y := 0; y < vram.height; y += 1 {
+		for x := 0; x < vram.width; x += 1 {
+			img.SetRGBA(x, y, vram.Read15(x, y))
+		}
+	}
+
+	return img
+}
+
+/* reads a pixel in 15bit direct display format */
+func (vram *VRAM) Read15(x int, y int) color.RGBA {
+	pix := uint32(vram.Read16(x, y))
+
+	return color.RGBA{
+		uint8(GetRange(pix, 0, 5) << 3),
+		uint8(GetRange(pix, 5, 5) << 3),
+		uint8(GetRange(pix, 10, 5) << 3),
+		0xff,
+	}
+}
+
+/*
+reads a pixel in 24bit direct display format; 24bit pixels are not aligned to halfwords so
+the x coordinate is counted in bytes
+
+	1st halfword: G0R0, 2nd halfword: R1B0, 3rd halfword: B1G1
+*/
+func (vram *VRAM) Read24(byteX int, y int) color.RGBA {
+	return color.RGBA{
+		vram.Read8(byteX, y),
+		vram.Read8(byteX+1, y),
+		vram.Read8(byteX+2, y),
+		0xff,
+	}
+}
+
+func (vram *VRAM) Read8(byteX int, y int) uint8 {
+	halfword := vram.Read16(Modulo(byteX/2, vram.width), y)
+
+	if byteX%2 == 1 {
+		return uint8(halfword >> 8)
+	}
+
+	return uint8(halfword)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"os"
+)
+
+/*
+Texture viewer
+
+Decodes a region of a texture page the way the gpu samples it (see GPU::GetTexel) so textures can be
+looked at without their clut applied by hand. The texture window, the texture cache and the draw
+statistics of the gpu are not affected.
+*/
+type TextureView struct {
+	PageX  int /* texture page in vram (multiple of 64 and 256 like GP0(E1h)) */
+	PageY  int
+	Format int /* TEXTURE_FORMAT_4b, TEXTURE_FORMAT_8b or TEXTURE_FORMAT_15b */
+	ClutX  int /* multiple of 16 */
+	ClutY  int
+
+	/* region of the page in texels */
+	U      int
+	V      int
+	Width  int
+	Height int
+}
+
+/* whole texture page */
+func NewTextureView(pageX, pageY, format, clutX, clutY int) *TextureView {
+	return &TextureView{
+		pageX,
+		pageY,
+		format,
+		clutX,
+		clutY,
+		0,
+		0,
+		256,
+		256,
+	}
+}
+
+/* texels per vram halfword */
+func (view *TextureView) TexelsPerHalfword() int {
+	switch view.Format {
+	case TEXTURE_FORMAT_4b:
+		return 4
+	case TEXTURE_FORMAT_8b:
+		return 2
+	default:
+		return 1
+	}
+}
+
+/* area of vram holding the texels of the region (x, y, width and height in halfwords) */
+func (view *TextureView) VRAMArea() (int, int, int, int) {
+	n := view.TexelsPerHalfword()
+
+	x1 := view.U / n
+	x2 := (view.U + view.Width + n - 1) / n
+
+	return view.PageX + x1, view.PageY + view.V, x2 - x1, view.Height
+}
+
+/* number of clut entries (0 for 15bit textures) */
+func (view *TextureView) ClutSize() int {
+	switch view.Format {
+	case TEXTURE_FORMAT_4b:
+		return 16
+	case TEXTURE_FORMAT_8b:
+		return 256
+	default:
+		return 0
+	}
+}
+
+/*
+Returns the texels of the region as an image; texels with value 0000h (which the gpu doesn't draw)
+are fully transparent
+*/
+func (gpu *GPU) DecodeTexture(view *TextureView) *image.RGBA {
+	// sample thru a copy so the texture window/cache of the real gpu stay untouched
+	probe := *gpu
+	probe.texCache = NewTextureCache(false)
+	probe.texWindowMaskX = 0
+	probe.texWindowMaskY = 0
+
+	img := image.NewRGBA(image.Rect(0, 0, view.Width, view.Height))
+
+	for y := 0; y < view.Height; y += 1 {
+		for x := 0; x < view.Width; x += 1 {
+			texel := probe.GetTexel(view.U+x, view.V+y, view.ClutX, view.ClutY, view.PageX, view.PageY, view.Format)
+			if texel == 0 {
+				continue
+			}
+
+			img.SetRGBA(x, y, color.RGBA{
+				uint8(GetRange(texel, 0, 5) << 3),
+				uint8(GetRange(texel, 5, 5) << 3),
+				uint8(GetRange(texel, 10, 5) << 3),
+				0xff,
+			})
+		}
+	}
+
+	return img
+}
+
+func (gpu *GPU) ExportTexturePNG(view *TextureView, path string) error {
+	return WritePNG(path, gpu.DecodeTexture(view))
+}
+
+/*
+	https://psx-spx.consoledev.net/cdromfileformats/#tim-psx-texture-image
+
+Writes the region (rounded out to whole halfwords) and its clut as a TIM file:
+
+	000h 4   ID (10h)
+	004h 4   Flags (bit0-2: 0=4bit, 1=8bit, 2=15bit, bit3: clut present)
+	...      CLUT block (only if flag bit3 is set)
+	...      image block
+
+Both blocks start with a 12 byte header (block size including header, vram x, vram y, width in halfwords,
+height) followed by the raw vram halfwords.
+*/
+func (gpu *GPU) WriteTIM(writer io.Writer, view *TextureView) error {
+	var flags uint32
+	PackRange(&flags, 0, uint32(view.Format), 3)
+	ModifyBit(&flags, 3, view.ClutSize() > 0)
+
+	if err := binary.Write(writer, binary.LittleEndian, []uint32{0x10, flags}); err != nil {
+		return err
+	}
+
+	if view.ClutSize() > 0 {
+		if err := gpu.WriteTIMBlock(writer, view.ClutX, view.ClutY, view.ClutSize(), 1); err != nil {
+			return err
+		}
+	}
+
+	x, y, width, height := view.VRAMArea()
+
+	return gpu.WriteTIMBlock(writer, x, y, width, height)
+}
+
+func (gpu *GPU) WriteTIMBlock(writer io.Writer, x, y, width, height int) error {
+	header := []uint32{
+		uint32(12 + width*height*2),
+		uint32(x) | uint32(y)<<16,
+		uint32(width) | uint32(height)<<16,
+	}
+
+	if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	data := make([]uint16, 0, width*height)
+	for row := 0; row < height; row += 1 {
+		for col := 0; col < width; col += 1 {
+			data = append(data, gpu.vram.ReadNative16((x+col)&(VRAM_WIDTH-1), (y+row)&(VRAM_HEIGHT-1)))
+		}
+	}
+
+	return binary.Write(writer, binary.LittleEndian, data)
+}
+
+func (gpu *GPU) ExportTextureTIM(view *TextureView, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gpu.WriteTIM(file, view)
+}
+
+/* area of vram scanned out to the screen (x, y, width and height in halfwords) */
+func (gpu *GPU) DisplayArea() (int, int, int, int) {
+	width := int(gpu.horizResolution)
+	if gpu.displayColourDepth {
+		// 3 bytes per pixel
+		width = width * 3 / 2
+	}
+
+	return gpu.displayVramStartX, gpu.displayVramStartY, width, int(gpu.vertResolution)
+}
+
+/* area of vram primitives are clipped to (x, y, width and height in halfwords) */
+func (gpu *GPU) DrawingArea() (int, int, int, int) {
+	return gpu.drawingAreaX1, gpu.drawingAreaY1, gpu.drawingAreaX2 - gpu.drawingAreaX1 + 1, gpu.drawingAreaY2 - gpu.drawingAreaY1 + 1
+}